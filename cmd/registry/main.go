@@ -2,18 +2,22 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"time"
 
+	"github.com/themobileprof/clipilot/server/catalog"
 	"github.com/themobileprof/clipilot/server/handlers"
 	"github.com/themobileprof/clipilot/server/middleware"
+	"github.com/themobileprof/clipilot/server/notify"
 )
 
 var (
@@ -34,6 +38,20 @@ func main() {
 	githubClientID := getEnv("GITHUB_CLIENT_ID", "")
 	githubClientSecret := getEnv("GITHUB_CLIENT_SECRET", "")
 	baseURL := getEnv("BASE_URL", "")
+	reportHideThreshold, err := strconv.Atoi(getEnv("REPORT_HIDE_THRESHOLD", "3"))
+	if err != nil {
+		log.Fatalf("Invalid REPORT_HIDE_THRESHOLD: %v", err)
+	}
+	reviewUploads, err := strconv.ParseBool(getEnv("REVIEW_UPLOADS", "true"))
+	if err != nil {
+		log.Fatalf("Invalid REVIEW_UPLOADS: %v", err)
+	}
+
+	// Admin subcommands run against the data directory and exit
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		runSeed(os.Args[2:], dataDir)
+		return
+	}
 
 	// Allow command-line flags to override environment variables
 	flag.StringVar(&port, "port", port, "Server port")
@@ -42,8 +60,17 @@ func main() {
 	flag.StringVar(&tmplDir, "templates", tmplDir, "Templates directory")
 	flag.StringVar(&adminUser, "admin", adminUser, "Admin username")
 	flag.StringVar(&adminPass, "password", adminPass, "Admin password (required)")
+	exportCatalog := flag.String("export-catalog", "", "Write the command catalog snapshot to this file and exit")
 	flag.Parse()
 
+	if *exportCatalog != "" {
+		if err := writeCatalogSnapshot(*exportCatalog); err != nil {
+			log.Fatalf("Failed to export catalog: %v", err)
+		}
+		fmt.Printf("✓ Catalog snapshot written to %s\n", *exportCatalog)
+		return
+	}
+
 	if adminPass == "" {
 		log.Fatal("Error: Admin password is required. Set ADMIN_PASSWORD env var or use --password flag")
 	}
@@ -64,15 +91,28 @@ func main() {
 
 	// Initialize handlers
 	h := handlers.New(handlers.Config{
-		UploadsDir:         uploadsDir,
-		DBPath:             dbPath,
-		StaticDir:          staticDir,
-		TemplateDir:        tmplDir,
-		AdminUser:          adminUser,
-		AdminPass:          adminPass,
-		GitHubClientID:     githubClientID,
-		GitHubClientSecret: githubClientSecret,
-		BaseURL:            baseURL,
+		UploadsDir:          uploadsDir,
+		DBPath:              dbPath,
+		StaticDir:           staticDir,
+		TemplateDir:         tmplDir,
+		AdminUser:           adminUser,
+		AdminPass:           adminPass,
+		GitHubClientID:      githubClientID,
+		GitHubClientSecret:  githubClientSecret,
+		BaseURL:             baseURL,
+		ReportHideThreshold: reportHideThreshold,
+		ReviewUploads:       reviewUploads,
+		Notifier: notify.New(notify.Config{
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUser:     getEnv("SMTP_USER", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:     getEnv("SMTP_FROM", ""),
+			AdminEmail:   getEnv("NOTIFY_ADMIN_EMAIL", ""),
+			WebhookURL:   getEnv("NOTIFY_WEBHOOK_URL", ""),
+		}),
+		MinClientVersion:    getEnv("CLIO_MIN_VERSION", ""),
+		LatestClientVersion: getEnv("CLIO_LATEST_VERSION", ""),
 	})
 
 	// Setup routes
@@ -97,17 +137,24 @@ func main() {
 		}
 	})
 	mux.HandleFunc("/api/v1/modules/changed", h.APIv1ChangedModules)
+	mux.HandleFunc("/api/v1/modules/changes", h.APIv1ChangedModules)
 	mux.HandleFunc("/api/v1/modules/", func(w http.ResponseWriter, r *http.Request) {
 		// Route to appropriate handler based on path suffix
 		path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
 		parts := strings.Split(path, "/")
 
-		if path == "changed" {
+		if path == "changed" || path == "changes" {
 			h.APIv1ChangedModules(w, r)
 		} else if len(parts) >= 2 && parts[1] == "download" {
 			h.APIv1DownloadModule(w, r)
 		} else if len(parts) >= 2 && parts[1] == "dependencies" {
 			h.APIv1ModuleDependencies(w, r)
+		} else if len(parts) >= 2 && parts[1] == "stats" {
+			h.APIv1ModuleStats(w, r)
+		} else if len(parts) >= 2 && parts[1] == "diff" {
+			h.APIv1ModuleDiff(w, r)
+		} else if len(parts) >= 2 && parts[1] == "graph" {
+			h.APIv1ModuleGraph(w, r)
 		} else if len(parts) == 1 && parts[0] != "" {
 			h.APIv1GetModule(w, r)
 		} else {
@@ -123,13 +170,46 @@ func main() {
 
 	// Protected routes (require authentication)
 	mux.HandleFunc("/upload", h.RequireAuth(h.UploadPage))
-	mux.HandleFunc("/api/upload", h.RequireAuth(h.APIUpload))
+	mux.HandleFunc("/api/upload", h.RequireAuthOrToken("module:upload", h.APIUpload))
+	mux.HandleFunc("/api/tokens", h.RequireAuth(h.APITokens))
 	mux.HandleFunc("/my-modules", h.RequireAuth(h.MyModules))
+	mux.HandleFunc("/drafts", h.RequireAuth(h.DraftsPage))
+	mux.HandleFunc("/drafts/edit", h.RequireAuth(h.DraftEditorPage))
+	mux.HandleFunc("/api/drafts/validate", h.RequireAuthOrToken("module:upload", h.APIValidateDraft))
+	mux.HandleFunc("/api/drafts/save", h.RequireAuth(h.APISaveDraft))
+	mux.HandleFunc("/api/drafts/publish", h.RequireAuth(h.APIPublishDraft))
+	mux.HandleFunc("/api/drafts/delete", h.RequireAuth(h.APIDeleteDraft))
 
 	geminiAPIKey := getEnv("GEMINI_API_KEY", "")
+	geminiDailyLimit, err := strconv.Atoi(getEnv("GEMINI_DAILY_LIMIT", "500"))
+	if err != nil {
+		log.Fatalf("Invalid GEMINI_DAILY_LIMIT: %v", err)
+	}
 
 	// Semantic search endpoint (public) - now cached
-	mux.HandleFunc("/api/commands/search", h.HandleSemanticSearch(geminiAPIKey))
+	mux.HandleFunc("/api/commands/search", h.HandleSemanticSearch(geminiAPIKey, geminiDailyLimit))
+
+	// Offline catalog snapshot (public)
+	mux.HandleFunc("/api/v1/commands/snapshot", h.APIv1CommandSnapshot)
+
+	// Paged command catalog (public)
+	mux.HandleFunc("/api/v1/commands", h.APIv1Commands)
+	mux.HandleFunc("/api/v1/commands/compare", h.APIv1CompareCommands)
+
+	// Search-as-you-type suggestions (public)
+	mux.HandleFunc("/api/suggest", h.APISuggest)
+
+	// Category taxonomy with synonyms (public)
+	mux.HandleFunc("/api/categories", h.APICategories)
+
+	// JSON Schema of the module YAML format (public)
+	mux.HandleFunc("/api/v1/schema/module.json", h.APIv1ModuleSchema)
+
+	// Snippets: short parameterized one-liners (GET public, POST logged in)
+	mux.HandleFunc("/api/v1/snippets", h.APISnippets)
+
+	// Client heartbeat: latest Clio version and supported minimum (public)
+	mux.HandleFunc("/api/client/check", h.APIClientCheck)
 
 	// Module request tracking (public POST, admin-only view)
 	mux.HandleFunc("/api/module-request", h.APIModuleRequest)
@@ -152,6 +232,16 @@ func main() {
 	mux.HandleFunc("/admin/users/create", h.CreateUser) // Admin only - create new user
 	mux.HandleFunc("/admin/users/delete", h.DeleteUser) // Admin only - delete user
 
+	// Abuse reports
+	mux.HandleFunc("/api/modules/report", h.RequireAuth(h.APIReportModule)) // Logged-in users - flag a module
+	mux.HandleFunc("/admin/reports", h.AdminReportsPage)                    // Admin only - review queue
+	mux.HandleFunc("/admin/reports/resolve", h.ResolveReports)              // Admin only - dismiss or keep hidden
+	mux.HandleFunc("/admin/review", h.AdminReviewPage)                      // Admin only - uploads awaiting approval
+	mux.HandleFunc("/admin/review/decide", h.ReviewDecision)                // Admin only - approve or reject with notes
+	mux.HandleFunc("/api/review", h.APIReview)                              // Admin only - review queue as JSON
+	mux.HandleFunc("/admin/categories", h.AdminCategoriesPage)              // Admin only - category taxonomy
+	mux.HandleFunc("/admin/categories/action", h.AdminCategoryAction)       // Admin only - add, rename, merge
+
 	// Static files
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
 
@@ -174,6 +264,7 @@ func main() {
 	fmt.Println("  - Clio Install: /clio (public)")
 	fmt.Println("  - Clio Upload: /api/install-script/upload (admin)")
 	fmt.Println("  - Users: /admin/users (admin)")
+	fmt.Println("  - Review: /admin/review (admin)")
 	fmt.Println("  - API Keys: /admin/api-keys (admin)")
 	fmt.Println()
 
@@ -183,6 +274,15 @@ func main() {
 	}
 }
 
+// writeCatalogSnapshot exports the embedded command catalog as JSON
+func writeCatalogSnapshot(path string) error {
+	data, err := json.MarshalIndent(catalog.NewSnapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
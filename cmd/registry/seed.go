@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/themobileprof/clipilot/server/migrations"
+	"github.com/themobileprof/clipilot/server/seed"
+
+	_ "modernc.org/sqlite"
+)
+
+// runSeed implements `registry seed`, filling the database with synthetic
+// modules and requests for load testing search, pagination and FTS
+func runSeed(args []string, dataDir string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	fs.StringVar(&dataDir, "data", dataDir, "Data directory")
+	modules := fs.Int("modules", 1000, "Number of modules to generate (each gets 1-3 versions)")
+	requests := fs.Int("requests", 5000, "Number of module requests to generate")
+	randSeed := fs.Int64("seed", 1, "Random seed, for reproducible datasets")
+	_ = fs.Parse(args)
+
+	uploadsDir := filepath.Join(dataDir, "uploads")
+	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
+		log.Fatalf("Failed to create uploads directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dataDir, "registry.db"))
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	initialSchema, err := migrations.GetInitialSchema()
+	if err != nil {
+		log.Fatalf("Failed to load initial schema: %v", err)
+	}
+	if _, err := db.Exec(initialSchema); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := migrations.EnsureColumns(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := migrations.EnsureChangeTriggers(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	start := time.Now()
+	res, err := seed.Run(db, seed.Options{
+		Modules:    *modules,
+		Requests:   *requests,
+		UploadsDir: uploadsDir,
+		RandSeed:   *randSeed,
+	})
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+	fmt.Printf("✓ Seeded %d modules (%d versions) and %d requests in %s\n",
+		res.Modules, res.Versions, res.Requests, time.Since(start).Round(time.Millisecond))
+	fmt.Printf("  Remove with: DELETE FROM modules WHERE uploaded_by = '%s'\n", seed.Uploader)
+}
@@ -0,0 +1,236 @@
+// Package gemini is a small client for the Gemini generateContent API with
+// retries, backoff and client-side rate limiting.
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultModel   = "gemini-2.0-flash"
+)
+
+// ErrEmptyResponse is returned when Gemini answers without any text
+var ErrEmptyResponse = errors.New("gemini: empty response")
+
+// APIError is a non-200 answer from the API
+type APIError struct {
+	StatusCode int
+	Status     string // Google RPC status, e.g. RESOURCE_EXHAUSTED
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Status != "" {
+		return fmt.Sprintf("gemini: %d %s: %s", e.StatusCode, e.Status, e.Message)
+	}
+	return fmt.Sprintf("gemini: status %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// GenerationConfig tunes a single generation
+type GenerationConfig struct {
+	Temperature     float64 `json:"temperature"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+}
+
+// Client calls one Gemini model. The zero value is not usable; use New.
+type Client struct {
+	APIKey     string
+	Model      string
+	BaseURL    string
+	HTTPClient *http.Client
+
+	MaxRetries  int           // attempts after the first one
+	BaseBackoff time.Duration // doubled on every retry, with jitter
+	MinInterval time.Duration // minimum spacing between requests (rate limit)
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// New returns a client for the default model with sensible retry and rate limits
+func New(apiKey string) *Client {
+	return &Client{
+		APIKey:      apiKey,
+		Model:       DefaultModel,
+		BaseURL:     DefaultBaseURL,
+		HTTPClient:  &http.Client{Timeout: 12 * time.Second},
+		MaxRetries:  2,
+		BaseBackoff: 500 * time.Millisecond,
+		MinInterval: 200 * time.Millisecond, // stays under the free tier's 300 RPM
+	}
+}
+
+type generateRequest struct {
+	Contents []content        `json:"contents"`
+	Config   GenerationConfig `json:"generationConfig"`
+}
+
+type content struct {
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text string `json:"text"`
+}
+
+type generateResponse struct {
+	Candidates []struct {
+		Content content `json:"content"`
+	} `json:"candidates"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// GenerateText sends a single-turn prompt and returns the first candidate's text
+func (c *Client) GenerateText(ctx context.Context, prompt string, cfg GenerationConfig) (string, error) {
+	body, err := json.Marshal(generateRequest{
+		Contents: []content{{Parts: []part{{Text: prompt}}}},
+		Config:   cfg,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return "", err
+			}
+		}
+		if err := c.wait(ctx); err != nil {
+			return "", err
+		}
+
+		text, err := c.do(ctx, body)
+		if err == nil {
+			return text, nil
+		}
+		lastErr = err
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !apiErr.Retryable() {
+			return "", err
+		}
+		if errors.Is(err, ErrEmptyResponse) || ctx.Err() != nil {
+			return "", err
+		}
+	}
+	return "", lastErr
+}
+
+func (c *Client) do(ctx context.Context, body []byte) (string, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent", strings.TrimRight(c.BaseURL, "/"), c.Model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+		var parsed errorResponse
+		if json.Unmarshal(raw, &parsed) == nil && parsed.Error.Message != "" {
+			apiErr.Status = parsed.Error.Status
+			apiErr.Message = parsed.Error.Message
+		}
+		if d, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return "", &retryAfterError{APIError: apiErr, after: time.Duration(d) * time.Second}
+		}
+		return "", apiErr
+	}
+
+	var parsed generateResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return "", fmt.Errorf("gemini: invalid response: %w", err)
+	}
+	if len(parsed.Candidates) == 0 || len(parsed.Candidates[0].Content.Parts) == 0 {
+		return "", ErrEmptyResponse
+	}
+	return parsed.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// retryAfterError carries the server's Retry-After hint alongside the API error
+type retryAfterError struct {
+	*APIError
+	after time.Duration
+}
+
+func (e *retryAfterError) Unwrap() error { return e.APIError }
+
+// backoff returns the delay before the given retry attempt
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	var ra *retryAfterError
+	if errors.As(lastErr, &ra) && ra.after > 0 {
+		return ra.after
+	}
+	d := c.BaseBackoff << (attempt - 1)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// wait blocks until the rate limiter allows another request
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	start := c.next
+	if start.Before(now) {
+		start = now
+	}
+	c.next = start.Add(c.MinInterval)
+	c.mu.Unlock()
+
+	return sleep(ctx, time.Until(start))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testClient(url string) *Client {
+	c := New("test-key")
+	c.BaseURL = url
+	c.BaseBackoff = 0
+	c.MinInterval = 0
+	return c
+}
+
+func TestGenerateTextRetriesServerErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing API key header")
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"[]"}]}}]}`))
+	}))
+	defer srv.Close()
+
+	text, err := testClient(srv.URL).GenerateText(context.Background(), "hi", GenerationConfig{})
+	if err != nil || text != "[]" || calls != 2 {
+		t.Fatalf("text=%q err=%v calls=%d", text, err, calls)
+	}
+}
+
+func TestGenerateTextDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := testClient(srv.URL).GenerateText(context.Background(), "hi", GenerationConfig{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != "INVALID_ARGUMENT" || calls != 1 {
+		t.Fatalf("err=%v calls=%d", err, calls)
+	}
+}
@@ -12,8 +12,32 @@ type Module struct {
 	SizeKB      int              `yaml:"size_kb" json:"size_kb"`
 	Flows       map[string]*Flow `yaml:"flows" json:"flows"`
 	Metadata    ModuleMetadata   `yaml:"metadata" json:"metadata"`
+
+	// TimeoutSeconds is the default command timeout for steps that do not
+	// set their own; 0 leaves commands unbounded
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+
+	// Env is added to the environment of every step command; a step's own
+	// env entries win on conflict. Values accept {{.key}} state templates.
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Environment declares what the module expects of the machine; Clio
+	// records the actual values at flow start and warns when they differ
+	Environment *Environment `yaml:"environment,omitempty" json:"environment,omitempty"`
 }
 
+// Environment lists the platforms, tool versions and free disk space a module
+// was written for, so failure reports carry what is needed to reproduce them
+type Environment struct {
+	Distros       []string          `yaml:"distros,omitempty" json:"distros,omitempty"`                   // linux, termux, macos, debian, fedora, arch
+	Tools         map[string]string `yaml:"tools,omitempty" json:"tools,omitempty"`                       // tool -> version constraint, e.g. ">=2.30, <3"
+	MinFreeDiskMB int               `yaml:"min_free_disk_mb,omitempty" json:"min_free_disk_mb,omitempty"` // checked against the working directory
+}
+
+// UninstallFlow is the conventional flow name a module uses to undo what its
+// other flows installed; Clio offers to run it when the module is removed
+const UninstallFlow = "uninstall"
+
 // ModuleMetadata contains module authorship and licensing info
 type ModuleMetadata struct {
 	Author  string `yaml:"author" json:"author"`
@@ -30,15 +54,57 @@ type Flow struct {
 // Step represents a single step in a flow
 type Step struct {
 	Key       string            `yaml:"-" json:"key"`     // Populated from map key
-	Type      string            `yaml:"type" json:"type"` // action, instruction, branch, terminal
+	Type      string            `yaml:"type" json:"type"` // action, instruction, branch, terminal, process, prompt, goto_flow
 	Message   string            `yaml:"message,omitempty" json:"message,omitempty"`
 	Command   string            `yaml:"command,omitempty" json:"command,omitempty"`
+	Rollback  string            `yaml:"rollback,omitempty" json:"rollback,omitempty"` // Undoes Command; replayed in reverse by clipilot rollback
+	SkipIf    string            `yaml:"skip_if,omitempty" json:"skip_if,omitempty"`   // Check command; exit 0 means the work is already done and the step is skipped
 	RunModule string            `yaml:"run_module,omitempty" json:"run_module,omitempty"`
+	Flow      string            `yaml:"flow,omitempty" json:"flow,omitempty"`         // For goto_flow type
 	BasedOn   string            `yaml:"based_on,omitempty" json:"based_on,omitempty"` // For branch type
 	Map       map[string]string `yaml:"map,omitempty" json:"map,omitempty"`           // For branch type
 	Next      string            `yaml:"next,omitempty" json:"next,omitempty"`
 	Validate  []Validation      `yaml:"validate,omitempty" json:"validate,omitempty"`
 	Condition *Condition        `yaml:"condition,omitempty" json:"condition,omitempty"`
+
+	// Background action steps are launched detached (nohup/systemd-run) and
+	// their PID is stored in state under PIDKey (default "<step>_pid").
+	// "process" steps use the same PIDKey to check or stop them.
+	Background bool   `yaml:"background,omitempty" json:"background,omitempty"`
+	PIDKey     string `yaml:"pid_key,omitempty" json:"pid_key,omitempty"`
+	Process    string `yaml:"process,omitempty" json:"process,omitempty"` // For process type: status, stop
+
+	// RequiresNetwork steps are preceded by a connectivity probe; when offline
+	// the runner skips the step with a warning or waits for retry (OnOffline)
+	RequiresNetwork bool   `yaml:"requires_network,omitempty" json:"requires_network,omitempty"`
+	OnOffline       string `yaml:"on_offline,omitempty" json:"on_offline,omitempty"` // skip, wait (default)
+
+	// Cwd and Env set the working directory and extra environment for the
+	// step's command; both accept {{.key}} state templates
+	Cwd string            `yaml:"cwd,omitempty" json:"cwd,omitempty"`
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// OnFailure is the recovery choice used without asking when the step
+	// fails in non-interactive mode: retry, skip or abort (default).
+	// OnError routes a failure that is not recovered to another step of
+	// the flow (cleanup, diagnostics) instead of aborting it.
+	OnFailure  string `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+	MaxRetries int    `yaml:"max_retries,omitempty" json:"max_retries,omitempty"` // For on_failure: retry
+	Retries    int    `yaml:"retries,omitempty" json:"retries,omitempty"`         // Alias of max_retries
+	RetryDelay string `yaml:"retry_delay,omitempty" json:"retry_delay,omitempty"` // Go duration between retries, e.g. 5s
+	OnError    string `yaml:"on_error,omitempty" json:"on_error,omitempty"`
+
+	// TimeoutSeconds kills the command's process group and fails the step
+	// when it runs longer; overrides the module's timeout_seconds
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+
+	// Prompt steps ask the user for a value and store it in state under Var,
+	// where later steps read it as {{.var}}. Pattern must match the whole
+	// answer; Secret input is not echoed or logged.
+	Var     string `yaml:"var,omitempty" json:"var,omitempty"`
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Default string `yaml:"default,omitempty" json:"default,omitempty"`
+	Secret  bool   `yaml:"secret,omitempty" json:"secret,omitempty"`
 }
 
 // Validation represents a step validation rule
@@ -47,6 +113,7 @@ type Validation struct {
 	ParseOutput  string `yaml:"parse_output,omitempty" json:"parse_output,omitempty"`
 	Expected     string `yaml:"expected,omitempty" json:"expected,omitempty"`
 	ErrorMessage string `yaml:"error_message,omitempty" json:"error_message,omitempty"`
+	WarnOnly     bool   `yaml:"warn_only,omitempty" json:"warn_only,omitempty"` // Failure is reported but does not abort the flow
 }
 
 // Condition represents a conditional execution rule
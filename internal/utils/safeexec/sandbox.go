@@ -0,0 +1,111 @@
+package safeexec
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+)
+
+// ErrNoSandbox is returned by SandboxCommand when neither bwrap nor firejail
+// is installed and the caller did not allow running unsandboxed
+var ErrNoSandbox = errors.New("no sandbox backend (bwrap or firejail) installed")
+
+// SandboxOptions controls how much of the host a sandboxed command can touch.
+type SandboxOptions struct {
+	// AllowNetwork keeps the host network namespace. Modules must declare
+	// network access explicitly; by default sandboxed commands run offline.
+	AllowNetwork bool
+	// WritablePaths are bind-mounted read-write on top of the read-only root.
+	WritablePaths []string
+	// AllowUnsandboxed lets SandboxCommand fall back to a plain Command when
+	// no backend is installed, or the installed one cannot start a sandbox,
+	// instead of returning ErrNoSandbox.
+	AllowUnsandboxed bool
+}
+
+// SandboxBackend returns the name of the sandbox tool available on this
+// system ("bwrap" or "firejail"), or an empty string if neither is installed.
+func SandboxBackend() string {
+	for _, name := range []string{"bwrap", "firejail"} {
+		if _, err := LookPath(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// SandboxCommand returns a Cmd that runs the named program inside bubblewrap or
+// firejail with a read-only root filesystem and, unless opts.AllowNetwork is set,
+// no network access. If no sandbox backend is installed it returns ErrNoSandbox,
+// unless opts.AllowUnsandboxed asks for a plain Command instead. With
+// AllowUnsandboxed the backend is also probed once, so a bwrap that cannot
+// create namespaces (common in containers) does not fail every command.
+func SandboxCommand(opts SandboxOptions, name string, arg ...string) (*exec.Cmd, error) {
+	backend := SandboxBackend()
+	if backend == "" || (opts.AllowUnsandboxed && !sandboxWorks(backend)) {
+		if !opts.AllowUnsandboxed {
+			return nil, ErrNoSandbox
+		}
+		return Command(name, arg...), nil
+	}
+	return Command(backend, sandboxArgs(backend, opts, name, arg)...), nil
+}
+
+var (
+	probeMu     sync.Mutex
+	probeResult = map[string]bool{}
+)
+
+// sandboxWorks reports whether backend can actually start a sandbox on this
+// host, by running "true" inside it. The result is cached per backend binary.
+func sandboxWorks(backend string) bool {
+	path, err := LookPath(backend)
+	if err != nil {
+		return false
+	}
+
+	probeMu.Lock()
+	defer probeMu.Unlock()
+	if ok, done := probeResult[path]; done {
+		return ok
+	}
+	ok := exec.Command(path, sandboxArgs(backend, SandboxOptions{}, "true", nil)...).Run() == nil
+	probeResult[path] = ok
+	return ok
+}
+
+// sandboxArgs builds the wrapper arguments for the given backend
+func sandboxArgs(backend string, opts SandboxOptions, name string, arg []string) []string {
+	var args []string
+
+	switch backend {
+	case "bwrap":
+		args = append(args,
+			"--ro-bind", "/", "/",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--unshare-all",
+			"--die-with-parent",
+		)
+		if opts.AllowNetwork {
+			args = append(args, "--share-net")
+		}
+		for _, p := range opts.WritablePaths {
+			args = append(args, "--bind", p, p)
+		}
+		args = append(args, "--")
+	case "firejail":
+		args = append(args, "--quiet", "--read-only=/", "--private-tmp")
+		if !opts.AllowNetwork {
+			args = append(args, "--net=none")
+		}
+		for _, p := range opts.WritablePaths {
+			args = append(args, "--read-write="+p)
+		}
+		args = append(args, "--")
+	}
+
+	args = append(args, name)
+	return append(args, arg...)
+}
@@ -0,0 +1,84 @@
+package safeexec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSandboxArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		opts    SandboxOptions
+		want    []string
+	}{
+		{
+			name:    "bwrap offline",
+			backend: "bwrap",
+			want: []string{"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp",
+				"--unshare-all", "--die-with-parent", "--", "ls", "-l"},
+		},
+		{
+			name:    "bwrap network and writable path",
+			backend: "bwrap",
+			opts:    SandboxOptions{AllowNetwork: true, WritablePaths: []string{"/srv/app"}},
+			want: []string{"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp",
+				"--unshare-all", "--die-with-parent", "--share-net", "--bind", "/srv/app", "/srv/app", "--", "ls", "-l"},
+		},
+		{
+			name:    "firejail offline",
+			backend: "firejail",
+			want:    []string{"--quiet", "--read-only=/", "--private-tmp", "--net=none", "--", "ls", "-l"},
+		},
+		{
+			name:    "firejail network and writable path",
+			backend: "firejail",
+			opts:    SandboxOptions{AllowNetwork: true, WritablePaths: []string{"/srv/app"}},
+			want:    []string{"--quiet", "--read-only=/", "--private-tmp", "--read-write=/srv/app", "--", "ls", "-l"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sandboxArgs(tt.backend, tt.opts, "ls", []string{"-l"}); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("sandboxArgs = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxCommandWithoutBackend(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if _, err := SandboxCommand(SandboxOptions{}, "ls"); err != ErrNoSandbox {
+		t.Fatalf("err = %v, want ErrNoSandbox", err)
+	}
+	cmd, err := SandboxCommand(SandboxOptions{AllowUnsandboxed: true}, "ls")
+	if err != nil || cmd == nil {
+		t.Fatalf("AllowUnsandboxed: cmd %v, err %v", cmd, err)
+	}
+}
+
+func TestSandboxCommandWithBrokenBackend(t *testing.T) {
+	dir := t.TempDir()
+	// A bwrap that cannot create namespaces fails before running anything
+	script := "#!/bin/sh\necho 'bwrap: No permissions to create new namespace' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "bwrap"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	cmd, err := SandboxCommand(SandboxOptions{AllowUnsandboxed: true}, "whatis", "ls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(cmd.Path) == "bwrap" {
+		t.Fatalf("AllowUnsandboxed still wraps with a broken bwrap: %v", cmd.Args)
+	}
+
+	// Callers that require confinement keep the wrapper and see it fail
+	cmd, err = SandboxCommand(SandboxOptions{}, "whatis", "ls")
+	if err != nil || filepath.Base(cmd.Path) != "bwrap" {
+		t.Fatalf("strict sandbox: cmd %v, err %v", cmd, err)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
@@ -30,6 +31,8 @@ type GitHubUserInfo struct {
 	Name      string
 }
 
+type contextKey struct{}
+
 const (
 	sessionCookie = "clipilot_session"
 	sessionTTL    = 24 * time.Hour
@@ -103,13 +106,14 @@ func (m *Manager) SetAdminSession(w http.ResponseWriter, username string, isAdmi
 	})
 }
 
-// SetGitHubSession creates a new session for GitHub user
-func (m *Manager) SetGitHubSession(w http.ResponseWriter, ghUser *GitHubUser) {
+// SetGitHubSession creates a new session for a GitHub user signed in as the
+// registry user username
+func (m *Manager) SetGitHubSession(w http.ResponseWriter, ghUser *GitHubUser, username string, isAdmin bool) {
 	token := m.generateToken()
 
 	session := &Session{
-		Username: ghUser.Login,
-		IsAdmin:  false,
+		Username: username,
+		IsAdmin:  isAdmin,
 		GitHubUser: &GitHubUserInfo{
 			Login:     ghUser.Login,
 			AvatarURL: ghUser.AvatarURL,
@@ -144,8 +148,24 @@ func (m *Manager) ClearSession(w http.ResponseWriter) {
 	})
 }
 
+// WithAPIUser returns r authenticated as username for the rest of the
+// request, for callers that verified an API token instead of a cookie
+func (m *Manager) WithAPIUser(r *http.Request, username string, isAdmin bool) *http.Request {
+	session := &Session{
+		Username:  username,
+		IsAdmin:   isAdmin,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, session))
+}
+
 // IsAuthenticated checks if request has valid session
 func (m *Manager) IsAuthenticated(r *http.Request) bool {
+	if _, ok := r.Context().Value(contextKey{}).(*Session); ok {
+		return true
+	}
+
 	cookie, err := r.Cookie(sessionCookie)
 	if err != nil {
 		return false
@@ -171,6 +191,10 @@ func (m *Manager) IsAuthenticated(r *http.Request) bool {
 
 // GetUsername returns username from session
 func (m *Manager) GetUsername(r *http.Request) string {
+	if session, ok := r.Context().Value(contextKey{}).(*Session); ok {
+		return session.Username
+	}
+
 	cookie, err := r.Cookie(sessionCookie)
 	if err != nil {
 		return ""
@@ -189,6 +213,10 @@ func (m *Manager) GetUsername(r *http.Request) string {
 
 // GetSession returns the full session
 func (m *Manager) GetSession(r *http.Request) *Session {
+	if session, ok := r.Context().Value(contextKey{}).(*Session); ok {
+		return session
+	}
+
 	cookie, err := r.Cookie(sessionCookie)
 	if err != nil {
 		return nil
@@ -11,6 +11,7 @@ import (
 
 	"github.com/themobileprof/clipilot/internal/models"
 	"github.com/themobileprof/clipilot/internal/utils/safeexec"
+	"github.com/themobileprof/clipilot/server/scanner"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -42,17 +43,20 @@ func SeedBuiltinModules(db *sql.DB, modulesDir string) error {
 			continue
 		}
 
+		riskLevel := scanner.ScanModule(&module).Level
+
 		// Insert or update (forcing file path to the builtin location)
 		_, err = db.Exec(`
 			INSERT INTO modules (
 				name, version, description, author, 
-				file_path, original_filename, uploaded_by, uploaded_at
-			) VALUES (?, ?, ?, ?, ?, ?, 'system', CURRENT_TIMESTAMP)
+				file_path, original_filename, uploaded_by, uploaded_at, risk_level
+			) VALUES (?, ?, ?, ?, ?, ?, 'system', CURRENT_TIMESTAMP, ?)
 			ON CONFLICT(name, version) DO UPDATE SET
 				file_path = excluded.file_path,
 				uploaded_by = 'system',
-				description = excluded.description
-		`, module.Name, module.Version, module.Description, module.Metadata.Author, path, entry.Name())
+				description = excluded.description,
+				risk_level = excluded.risk_level
+		`, module.Name, module.Version, module.Description, module.Metadata.Author, path, entry.Name(), riskLevel)
 
 		if err != nil {
 			log.Printf("Warning: failed to seed %s: %v", module.Name, err)
@@ -97,6 +101,15 @@ func DiscoverAndSubmitCommands(db *sql.DB, minCommands int) error {
 
 	log.Printf("🔍 Discovered %d commands on server", len(commands))
 
+	// Drop junk binaries and placeholder descriptions before they reach the queue
+	commands, rejected := dedupeSubmissions(commands)
+	for reason, count := range rejected {
+		log.Printf("   Skipped %d commands: %s", count, reason)
+	}
+	if len(commands) == 0 {
+		return fmt.Errorf("no commands passed submission filtering")
+	}
+
 	// Begin transaction
 	tx, err := db.Begin()
 	if err != nil {
@@ -309,8 +322,12 @@ func scanPATHDirectories() []string {
 
 // getCommandDescription gets description from whatis
 func getCommandDescription(cmdName string) string {
-	// Try whatis first (with timeout)
-	cmd := safeexec.Command("whatis", cmdName)
+	// Try whatis first, confined when a working sandbox is available since it runs
+	// once per discovered command on the server host
+	cmd, err := safeexec.SandboxCommand(safeexec.SandboxOptions{AllowUnsandboxed: true}, "whatis", cmdName)
+	if err != nil {
+		return "No description available"
+	}
 	output, err := cmd.Output()
 	if err == nil {
 		lines := strings.Split(string(output), "\n")
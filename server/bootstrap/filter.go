@@ -0,0 +1,111 @@
+package bootstrap
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Rejection reasons returned by FilterSubmission
+const (
+	RejectBadName     = "invalid command name"
+	RejectBlacklisted = "matches blacklist pattern"
+	RejectDescription = "description too short or placeholder"
+)
+
+var validCommandName = regexp.MustCompile(`^[a-z0-9][a-z0-9._+-]{0,39}$`)
+
+// blacklist catches helper binaries, shared objects and generated names that
+// show up on PATH but are never useful to enhance
+var blacklist = []*regexp.Regexp{
+	regexp.MustCompile(`\.so(\.\d+)*$`),               // shared objects
+	regexp.MustCompile(`^[0-9a-f]{12,}$`),             // hashes
+	regexp.MustCompile(`^[0-9._-]+$`),                 // numbers only
+	regexp.MustCompile(`^(x86_64|aarch64|i686|arm)-`), // cross-toolchain prefixes
+	regexp.MustCompile(`\.(py|pl|rb|sh|dll|exe)$`),    // script/binary extensions
+	regexp.MustCompile(`^(systemd-.*-generator|kworker)`),
+}
+
+// placeholderDescriptions are fallbacks written when no real description was found
+var placeholderDescriptions = map[string]bool{
+	"command line utility":     true,
+	"no description available": true,
+}
+
+const minDescriptionLength = 10
+
+// NormalizeCommandName lowercases and trims a command name. Version
+// suffixes are kept: "python2.7" and "gcc-12" are distinct binaries.
+func NormalizeCommandName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+var versionSuffix = regexp.MustCompile(`([.-]\d+)+$`)
+
+// baseCommandName strips a trailing version suffix, so "python3.11" becomes
+// "python3" and "gcc-12" becomes "gcc"
+func baseCommandName(name string) string {
+	return versionSuffix.ReplaceAllString(name, "")
+}
+
+// FilterSubmission checks a command name and description before it is queued
+// for enhancement. It returns the normalised name, or a rejection reason.
+func FilterSubmission(name, description string) (string, string) {
+	normalized := NormalizeCommandName(name)
+	if !validCommandName.MatchString(normalized) {
+		return "", RejectBadName
+	}
+	for _, pattern := range blacklist {
+		if pattern.MatchString(strings.ToLower(name)) {
+			return "", RejectBlacklisted
+		}
+	}
+	desc := strings.TrimSpace(description)
+	if len(desc) < minDescriptionLength || placeholderDescriptions[strings.ToLower(desc)] {
+		return "", RejectDescription
+	}
+	return normalized, ""
+}
+
+// dedupeSubmissions filters a name->description map and keeps one entry per
+// normalised name, preferring the longest description. A versioned name is
+// folded into its base name only when the base itself was submitted, so
+// "gcc-12" merges with "gcc" but a lone "python2.7" stays as it is.
+func dedupeSubmissions(commands map[string]string) (map[string]string, map[string]int) {
+	accepted := make(map[string]string)
+	rejected := make(map[string]int)
+	for name, desc := range commands {
+		normalized, reason := FilterSubmission(name, desc)
+		if reason != "" {
+			rejected[reason]++
+			continue
+		}
+		desc = strings.TrimSpace(desc)
+		if existing, ok := accepted[normalized]; ok && !longerDescription(desc, existing) {
+			continue
+		}
+		accepted[normalized] = desc
+	}
+
+	kept := make(map[string]string)
+	for name, desc := range accepted {
+		if base := baseCommandName(name); base != name {
+			if _, ok := accepted[base]; ok {
+				name = base
+			}
+		}
+		if existing, ok := kept[name]; ok && !longerDescription(desc, existing) {
+			continue
+		}
+		kept[name] = desc
+	}
+	return kept, rejected
+}
+
+// longerDescription reports whether a should replace b, breaking length ties
+// alphabetically so the result does not depend on map order
+func longerDescription(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a < b
+}
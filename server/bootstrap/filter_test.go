@@ -0,0 +1,46 @@
+package bootstrap
+
+import "testing"
+
+func TestFilterSubmission(t *testing.T) {
+	cases := []struct {
+		name, desc, wantName, wantReason string
+	}{
+		{"grep", "print lines that match patterns", "grep", ""},
+		{"gcc-12", "GNU project C and C++ compiler", "gcc-12", ""},
+		{"Python2.7", "an interpreted, interactive language", "python2.7", ""},
+		{"libfoo.so.1", "shared helper library", "", RejectBlacklisted},
+		{"3f9a0c2d5e7b1a4c", "generated helper", "", RejectBlacklisted},
+		{"x86_64-linux-gnu-ld", "The GNU linker for cross builds", "", RejectBlacklisted},
+		{"Bad Name!", "something descriptive here", "", RejectBadName},
+		{"mytool", "Command line utility", "", RejectDescription},
+		{"mytool", "short", "", RejectDescription},
+	}
+	for _, c := range cases {
+		gotName, gotReason := FilterSubmission(c.name, c.desc)
+		if gotName != c.wantName || gotReason != c.wantReason {
+			t.Errorf("FilterSubmission(%q, %q) = (%q, %q), want (%q, %q)",
+				c.name, c.desc, gotName, gotReason, c.wantName, c.wantReason)
+		}
+	}
+}
+
+func TestDedupeSubmissions(t *testing.T) {
+	kept, rejected := dedupeSubmissions(map[string]string{
+		"gcc":       "C compiler tool",
+		"gcc-12":    "GNU project C and C++ compiler",
+		"python2.7": "an interpreted, interactive language",
+		"foo.so":    "a shared object file",
+		"nothing":   "No description available",
+	})
+	want := map[string]string{
+		"gcc":       "GNU project C and C++ compiler",
+		"python2.7": "an interpreted, interactive language",
+	}
+	if len(kept) != len(want) || kept["gcc"] != want["gcc"] || kept["python2.7"] != want["python2.7"] {
+		t.Fatalf("kept = %v, want %v", kept, want)
+	}
+	if rejected[RejectBlacklisted] != 1 || rejected[RejectDescription] != 1 {
+		t.Fatalf("rejected = %v", rejected)
+	}
+}
@@ -1,10 +1,14 @@
 package catalog
 
 import (
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
+	"encoding/json"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,13 +18,21 @@ var embeddedYAML []byte
 
 // CommandEntry is one row from the common commands catalog.
 type CommandEntry struct {
-	Name          string `yaml:"name"`
-	Description   string `yaml:"description"`
-	Category      string `yaml:"category"`
-	Keywords      string `yaml:"keywords"`
-	PkgPackage    string `yaml:"pkg_package"`
-	Priority      int    `yaml:"priority"`
-	AlternativeTo string `yaml:"alternative_to"`
+	Name          string `yaml:"name" json:"name"`
+	Description   string `yaml:"description" json:"description"`
+	Category      string `yaml:"category" json:"category"`
+	Keywords      string `yaml:"keywords" json:"keywords"`
+	AptPackage    string `yaml:"apt_package" json:"apt_package,omitempty"`
+	PkgPackage    string `yaml:"pkg_package" json:"pkg_package,omitempty"`
+	DnfPackage    string `yaml:"dnf_package" json:"dnf_package,omitempty"`
+	BrewPackage   string `yaml:"brew_package" json:"brew_package,omitempty"`
+	ArchPackage   string `yaml:"arch_package" json:"arch_package,omitempty"`
+	Homepage      string `yaml:"homepage" json:"homepage,omitempty"`
+	Priority      int    `yaml:"priority" json:"priority"`
+	AlternativeTo string `yaml:"alternative_to" json:"alternative_to,omitempty"`
+	// Platforms limits where the command exists (linux, termux, macos, or a
+	// distro family: debian, fedora, arch). Empty means everywhere.
+	Platforms []string `yaml:"platforms" json:"platforms,omitempty"`
 }
 
 // SearchResult is a scored catalog hit for API responses.
@@ -31,6 +43,7 @@ type SearchResult struct {
 
 var (
 	entries     []CommandEntry
+	version     string
 	entriesOnce sync.Once
 )
 
@@ -40,19 +53,157 @@ var essentials = []CommandEntry{
 	{Name: "pwd", Description: "Print current working directory", Category: "file-management", Keywords: "where, directory, folder, path, location", Priority: 90},
 	{Name: "ps", Description: "List running processes", Category: "system", Keywords: "process, running, apps, programs, tasks", Priority: 90},
 	{Name: "kill", Description: "Stop a running process", Category: "system", Keywords: "stop, stuck, jam, hang, frozen, process", Priority: 88},
-	{Name: "pkg", Description: "Termux package manager", Category: "system", Keywords: "install, update, upgrade, package, termux", PkgPackage: "pkg", Priority: 92},
+	{Name: "pkg", Description: "Termux package manager", Category: "system", Keywords: "install, update, upgrade, package, termux", PkgPackage: "pkg", Priority: 92, Platforms: []string{"termux"}},
 }
 
 func loadEntries() []CommandEntry {
 	entriesOnce.Do(func() {
-		_ = yaml.Unmarshal(embeddedYAML, &entries)
-		entries = append(entries, essentials...)
+		var parsed []CommandEntry
+		_ = yaml.Unmarshal(embeddedYAML, &parsed)
+		entries = dedupeEntries(append(parsed, essentials...))
+		data, _ := json.Marshal(entries)
+		sum := sha256.Sum256(data)
+		version = hex.EncodeToString(sum[:])[:16]
 	})
 	return entries
 }
 
+// dedupeEntries collapses entries that name the same command, whichever
+// source they come from. The merged entry keeps the first one's position.
+func dedupeEntries(in []CommandEntry) []CommandEntry {
+	index := map[string]int{}
+	out := make([]CommandEntry, 0, len(in))
+	for _, e := range in {
+		key := strings.ToLower(strings.TrimSpace(e.Name))
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, e)
+			continue
+		}
+		out[i] = mergeEntries(out[i], e)
+	}
+	return out
+}
+
+// mergeEntries combines two entries for one command: the richer entry wins
+// and the other only fills the fields it leaves empty.
+func mergeEntries(a, b CommandEntry) CommandEntry {
+	if richness(b) > richness(a) {
+		a, b = b, a
+	}
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&a.Description, b.Description)
+	fill(&a.Category, b.Category)
+	fill(&a.AptPackage, b.AptPackage)
+	fill(&a.PkgPackage, b.PkgPackage)
+	fill(&a.DnfPackage, b.DnfPackage)
+	fill(&a.BrewPackage, b.BrewPackage)
+	fill(&a.ArchPackage, b.ArchPackage)
+	fill(&a.Homepage, b.Homepage)
+	fill(&a.AlternativeTo, b.AlternativeTo)
+	a.Keywords = mergeKeywords(a.Keywords, b.Keywords)
+	if len(a.Platforms) == 0 {
+		a.Platforms = b.Platforms
+	}
+	if b.Priority > a.Priority {
+		a.Priority = b.Priority
+	}
+	return a
+}
+
+// richness counts the metadata fields an entry fills in
+func richness(e CommandEntry) int {
+	n := 0
+	for _, field := range []string{e.Description, e.Category, e.Keywords, e.AptPackage, e.PkgPackage,
+		e.DnfPackage, e.BrewPackage, e.ArchPackage, e.Homepage, e.AlternativeTo} {
+		if strings.TrimSpace(field) != "" {
+			n++
+		}
+	}
+	if len(e.Platforms) > 0 {
+		n++
+	}
+	return n
+}
+
+// mergeKeywords joins two comma-separated keyword lists without repeats.
+func mergeKeywords(a, b string) string {
+	seen := map[string]bool{}
+	var out []string
+	for _, list := range []string{a, b} {
+		for _, kw := range strings.Split(list, ",") {
+			kw = strings.TrimSpace(kw)
+			if kw != "" && !seen[kw] {
+				seen[kw] = true
+				out = append(out, kw)
+			}
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// Snapshot is a self-contained export of the catalog for offline installs.
+type Snapshot struct {
+	Version     string         `json:"version"`
+	GeneratedAt string         `json:"generated_at"`
+	Count       int            `json:"count"`
+	Commands    []CommandEntry `json:"commands"`
+}
+
+// All returns every catalog entry, including the built-in essentials.
+func All() []CommandEntry {
+	src := loadEntries()
+	out := make([]CommandEntry, len(src))
+	copy(out, src)
+	return out
+}
+
+// Categories returns the distinct categories used by the catalog, sorted.
+func Categories() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, entry := range loadEntries() {
+		cat := strings.ToLower(strings.TrimSpace(entry.Category))
+		if cat != "" && !seen[cat] {
+			seen[cat] = true
+			out = append(out, cat)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Version identifies the merged catalog, essentials included (first 16 hex
+// chars of the SHA-256 of its entries).
+func Version() string {
+	loadEntries()
+	return version
+}
+
+// NewSnapshot builds a snapshot of the full catalog.
+func NewSnapshot() Snapshot {
+	commands := All()
+	return Snapshot{
+		Version:     Version(),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Count:       len(commands),
+		Commands:    commands,
+	}
+}
+
 // Search finds commands matching a natural-language query.
 func Search(query string) []SearchResult {
+	return SearchOn(query, "")
+}
+
+// SearchOn is Search restricted to commands that can exist on os (see
+// AvailableOn).
+func SearchOn(query, os string) []SearchResult {
 	tokens := tokenize(query)
 	if len(tokens) == 0 {
 		return nil
@@ -60,6 +211,9 @@ func Search(query string) []SearchResult {
 
 	var results []SearchResult
 	for _, entry := range loadEntries() {
+		if !AvailableOn(entry, os) {
+			continue
+		}
 		score := scoreEntry(entry, tokens, query)
 		if score >= minScore {
 			results = append(results, SearchResult{Entry: entry, Score: score})
@@ -73,6 +227,33 @@ func Search(query string) []SearchResult {
 	return results
 }
 
+// Complete returns up to limit commands whose name starts with prefix,
+// highest priority first. It is cheap enough to call on every keystroke.
+func Complete(prefix string, limit int) []CommandEntry {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" || limit <= 0 {
+		return nil
+	}
+
+	var matches []CommandEntry
+	for _, entry := range loadEntries() {
+		if strings.HasPrefix(strings.ToLower(entry.Name), prefix) {
+			matches = append(matches, entry)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Priority != matches[j].Priority {
+			return matches[i].Priority > matches[j].Priority
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
 const minScore = 2.5
 
 func scoreEntry(entry CommandEntry, tokens []string, rawQuery string) float64 {
@@ -138,6 +319,77 @@ func sortResults(results []SearchResult) {
 	})
 }
 
+// Alternatives returns the sorted names of commands that can stand in for
+// name, in either direction of alternative_to: the ones it lists and the
+// ones that list it.
+func Alternatives(name string) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	seen := map[string]bool{}
+	var out []string
+	add := func(alt string) {
+		alt = strings.ToLower(strings.TrimSpace(alt))
+		if alt != "" && alt != name && !seen[alt] {
+			seen[alt] = true
+			out = append(out, alt)
+		}
+	}
+	for _, entry := range loadEntries() {
+		entryName := strings.ToLower(entry.Name)
+		for _, alt := range strings.Split(entry.AlternativeTo, ",") {
+			alt = strings.ToLower(strings.TrimSpace(alt))
+			if entryName == name {
+				add(alt)
+			} else if alt == name {
+				add(entryName)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// platformsFor maps a client-reported OS or distro to the platform names it
+// satisfies. Plain "linux" is ambiguous (Termux clients report it too) so it
+// satisfies every Linux flavour. Anything unrecognised (windows, freebsd, ...)
+// only satisfies entries that name it exactly.
+func platformsFor(os string) []string {
+	os = strings.ToLower(strings.TrimSpace(os))
+	switch {
+	case strings.Contains(os, "android") || os == "termux":
+		return []string{"termux"}
+	case os == "darwin" || strings.HasPrefix(os, "mac"):
+		return []string{"macos"}
+	case os == "debian" || os == "ubuntu" || os == "linuxmint" || os == "pop" || os == "raspbian":
+		return []string{"linux", "debian"}
+	case os == "fedora" || os == "rhel" || os == "centos" || os == "rocky" || os == "almalinux":
+		return []string{"linux", "fedora"}
+	case os == "arch" || os == "manjaro" || os == "endeavouros":
+		return []string{"linux", "arch"}
+	case os == "alpine" || os == "gentoo" || os == "void" || os == "nixos" || strings.HasPrefix(os, "opensuse"):
+		return []string{"linux"}
+	case os == "linux":
+		return []string{"linux", "termux", "debian", "fedora", "arch"}
+	default:
+		return []string{os}
+	}
+}
+
+// AvailableOn reports whether entry can exist on the client's os. Entries
+// without platform constraints, and an empty os, always match.
+func AvailableOn(entry CommandEntry, os string) bool {
+	if len(entry.Platforms) == 0 || strings.TrimSpace(os) == "" {
+		return true
+	}
+	for _, have := range platformsFor(os) {
+		for _, want := range entry.Platforms {
+			if strings.EqualFold(want, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // UseCase returns a practical usage hint for the client.
 func UseCase(entry CommandEntry, os string) string {
 	switch entry.Name {
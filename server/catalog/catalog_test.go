@@ -1,6 +1,13 @@
 package catalog
 
-import "testing"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestSearchDiskSpace(t *testing.T) {
 	hits := Search("how much storage is left on my phone")
@@ -36,3 +43,152 @@ func TestTokenizePidgin(t *testing.T) {
 		t.Fatalf("expected tokens, got %v", tokens)
 	}
 }
+
+func TestCompletePrefix(t *testing.T) {
+	got := Complete("pi", 5)
+	if len(got) == 0 {
+		t.Fatal("expected completions for 'pi'")
+	}
+	for _, e := range got {
+		if e.Name[:2] != "pi" {
+			t.Fatalf("completion %q does not start with 'pi'", e.Name)
+		}
+	}
+	if len(Complete("", 5)) != 0 {
+		t.Fatal("empty prefix should return nothing")
+	}
+}
+
+func TestAllHasUniqueNames(t *testing.T) {
+	seen := map[string]bool{}
+	for _, e := range All() {
+		if seen[e.Name] {
+			t.Fatalf("duplicate catalog entry %q", e.Name)
+		}
+		seen[e.Name] = true
+	}
+	for _, e := range All() {
+		if e.Name == "ps" && (!strings.Contains(e.Keywords, "apps") || strings.Count(e.Keywords, "running") != 1) {
+			t.Fatalf("ps keywords not merged from essentials: %q", e.Keywords)
+		}
+	}
+}
+
+func TestDedupeEntriesRichestWins(t *testing.T) {
+	sparse := CommandEntry{Name: "ssh", Description: "ssh", Keywords: "remote", Priority: 90}
+	rich := CommandEntry{Name: "ssh", Description: "Secure remote login", Category: "networking",
+		Keywords: "login, remote", AptPackage: "openssh-client", PkgPackage: "openssh", Priority: 80}
+	other := CommandEntry{Name: "scp"}
+
+	for _, in := range [][]CommandEntry{{sparse, other, rich}, {rich, other, sparse}} {
+		out := dedupeEntries(in)
+		if len(out) != 2 {
+			t.Fatalf("dedupeEntries kept %d entries, want 2", len(out))
+		}
+		got := out[0]
+		if got.Name != "ssh" || out[1].Name != "scp" {
+			t.Fatalf("merged entry moved: %+v", out)
+		}
+		if got.Description != "Secure remote login" || got.AptPackage != "openssh-client" || got.Category != "networking" {
+			t.Errorf("richer entry did not win: %+v", got)
+		}
+		if got.Keywords != "login, remote" || got.Priority != 90 {
+			t.Errorf("keywords/priority = %q/%d, want merged login, remote/90", got.Keywords, got.Priority)
+		}
+	}
+}
+
+func TestSearchOnPlatform(t *testing.T) {
+	for _, hit := range SearchOn("install package", "fedora") {
+		if hit.Entry.Name == "apt" || hit.Entry.Name == "pkg" || hit.Entry.Name == "brew" {
+			t.Fatalf("%s suggested on fedora", hit.Entry.Name)
+		}
+	}
+	found := false
+	for _, hit := range SearchOn("install package", "android") {
+		found = found || hit.Entry.Name == "pkg"
+	}
+	if !found {
+		t.Fatal("pkg not suggested on android")
+	}
+}
+
+func TestAvailableOn(t *testing.T) {
+	apt := CommandEntry{Name: "apt", Platforms: []string{"debian", "termux"}}
+	ls := CommandEntry{Name: "ls", Platforms: []string{"linux", "termux", "macos"}}
+	winget := CommandEntry{Name: "winget", Platforms: []string{"windows"}}
+	cases := []struct {
+		entry CommandEntry
+		os    string
+		want  bool
+	}{
+		{apt, "", true},
+		{apt, "linux", true},
+		{apt, "ubuntu", true},
+		{apt, "android", true},
+		{apt, "fedora", false},
+		{apt, "darwin", false},
+		{apt, "windows", false},
+		{apt, "freebsd", false},
+		{ls, "alpine", true},
+		{ls, "opensuse-leap", true},
+		{ls, "windows", false},
+		{winget, "Windows", true},
+		{winget, "linux", false},
+	}
+	for _, c := range cases {
+		if got := AvailableOn(c.entry, c.os); got != c.want {
+			t.Errorf("AvailableOn(%s, %q) = %v, want %v", c.entry.Name, c.os, got, c.want)
+		}
+	}
+	if !AvailableOn(CommandEntry{Name: "ls"}, "darwin") {
+		t.Error("unconstrained entry should be available everywhere")
+	}
+}
+
+func TestAlternatives(t *testing.T) {
+	got := strings.Join(Alternatives("htop"), ",")
+	if got != "btop,top" {
+		t.Fatalf("Alternatives(htop) = %q, want btop,top", got)
+	}
+	if len(Alternatives("no-such-command")) != 0 {
+		t.Fatal("unknown command should have no alternatives")
+	}
+}
+
+func TestAllIncludesEssentials(t *testing.T) {
+	all := All()
+	found := false
+	for _, e := range all {
+		found = found || e.Name == "pkg"
+	}
+	if !found {
+		t.Fatal("All is missing the pkg essential")
+	}
+	all[0].Name = "changed"
+	if All()[0].Name == "changed" {
+		t.Fatal("All returned the shared slice")
+	}
+}
+
+func TestVersionTracksMergedEntries(t *testing.T) {
+	v := Version()
+	if len(v) != 16 || v != Version() {
+		t.Fatalf("Version = %q, want a stable 16 char hash", v)
+	}
+	data, _ := json.Marshal(loadEntries())
+	sum := sha256.Sum256(data)
+	if want := hex.EncodeToString(sum[:])[:16]; v != want {
+		t.Fatalf("Version = %s, want the hash of the merged entries %s", v, want)
+	}
+}
+
+func TestNewSnapshot(t *testing.T) {
+	snap := NewSnapshot()
+	if snap.Version != Version() || snap.Count != len(All()) || len(snap.Commands) != snap.Count {
+		t.Fatalf("snapshot version/count = %s/%d with %d commands", snap.Version, snap.Count, len(snap.Commands))
+	}
+	if _, err := time.Parse(time.RFC3339, snap.GeneratedAt); err != nil {
+		t.Fatalf("generated_at %q: %v", snap.GeneratedAt, err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package flowgraph renders a module's flows as a Graphviz DOT graph so a
+// module can be audited without running it.
+package flowgraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+// maxLabelCommand is how much of a command is shown inside a node
+const maxLabelCommand = 60
+
+// DOT returns one cluster per flow with a node per step. Solid edges follow
+// next and branch choices, dashed edges on_error routes, bold edges jumps to
+// other flows; run_module calls point at a separate module node.
+func DOT(module *models.Module) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", quote(module.Name))
+	b.WriteString("  rankdir=TB;\n  node [shape=box, fontname=\"monospace\"];\n")
+
+	subModules := map[string]bool{}
+	for _, flowName := range sortedKeys(module.Flows) {
+		flow := module.Flows[flowName]
+		if flow == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "  subgraph %s {\n", quote("cluster_"+flowName))
+		fmt.Fprintf(&b, "    label=%s;\n", quote(flowName))
+		startID := nodeID(flowName, "__start")
+		fmt.Fprintf(&b, "    %s [shape=point];\n", startID)
+		if flow.Start != "" {
+			fmt.Fprintf(&b, "    %s -> %s;\n", startID, nodeID(flowName, flow.Start))
+		}
+		for _, key := range sortedKeys(flow.Steps) {
+			step := flow.Steps[key]
+			if step == nil {
+				continue
+			}
+			fmt.Fprintf(&b, "    %s [label=%s%s];\n", nodeID(flowName, key), quote(label(key, step)), shape(step))
+		}
+		b.WriteString("  }\n")
+
+		for _, key := range sortedKeys(flow.Steps) {
+			step := flow.Steps[key]
+			if step == nil {
+				continue
+			}
+			from := nodeID(flowName, key)
+			if step.Next != "" {
+				fmt.Fprintf(&b, "  %s -> %s;\n", from, nodeID(flowName, step.Next))
+			}
+			for _, choice := range sortedKeys(step.Map) {
+				fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", from, nodeID(flowName, step.Map[choice]), quote(choice))
+			}
+			if step.OnError != "" {
+				fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=\"on_error\"];\n", from, nodeID(flowName, step.OnError))
+			}
+			if step.Type == "goto_flow" && step.Flow != "" {
+				if target := module.Flows[step.Flow]; target != nil {
+					fmt.Fprintf(&b, "  %s -> %s [style=bold];\n", from, nodeID(step.Flow, "__start"))
+				}
+			}
+			if step.RunModule != "" {
+				subModules[step.RunModule] = true
+				fmt.Fprintf(&b, "  %s -> %s [style=dotted];\n", from, quote("module:"+step.RunModule))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(subModules) {
+		fmt.Fprintf(&b, "  %s [shape=component, label=%s];\n", quote("module:"+name), quote(name))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func label(key string, step *models.Step) string {
+	lines := []string{key + " (" + step.Type + ")"}
+	if cmd := strings.TrimSpace(step.Command); cmd != "" {
+		if i := strings.IndexByte(cmd, '\n'); i >= 0 {
+			cmd = cmd[:i] + " ..."
+		}
+		if len(cmd) > maxLabelCommand {
+			cmd = cmd[:maxLabelCommand] + "..."
+		}
+		lines = append(lines, "$ "+cmd)
+	}
+	if step.RunModule != "" {
+		lines = append(lines, "runs "+step.RunModule)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func shape(step *models.Step) string {
+	switch step.Type {
+	case "branch":
+		return ", shape=diamond"
+	case "terminal":
+		return ", shape=doublecircle"
+	case "prompt":
+		return ", shape=parallelogram"
+	}
+	return ""
+}
+
+func nodeID(flow, step string) string {
+	return quote(flow + "/" + step)
+}
+
+// quote returns s as a DOT double-quoted string
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
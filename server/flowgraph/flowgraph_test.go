@@ -0,0 +1,49 @@
+package flowgraph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+func TestDOT(t *testing.T) {
+	module := &models.Module{
+		Name: "web_setup",
+		Flows: map[string]*models.Flow{
+			"main": {
+				Start: "check",
+				Steps: map[string]*models.Step{
+					"check":   {Type: "branch", BasedOn: "os", Map: map[string]string{"termux": "install"}},
+					"install": {Type: "action", Command: "pkg install \"nginx\"", OnError: "done", Next: "more"},
+					"more":    {Type: "goto_flow", Flow: "configure"},
+					"done":    {Type: "terminal", Message: "Failed"},
+				},
+			},
+			"configure": {
+				Start: "deps",
+				Steps: map[string]*models.Step{
+					"deps": {Type: "action", Command: "true", RunModule: "git_setup"},
+				},
+			},
+		},
+	}
+
+	dot := DOT(module)
+	for _, want := range []string{
+		`digraph "web_setup" {`,
+		`"main/__start" -> "main/check";`,
+		`"main/check" -> "main/install" [label="termux"];`,
+		`$ pkg install \"nginx\"`,
+		`"main/install" -> "main/done" [style=dashed, label="on_error"];`,
+		`"main/more" -> "configure/__start" [style=bold];`,
+		`"module:git_setup" [shape=component`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot)
+		}
+	}
+	if DOT(module) != dot {
+		t.Error("DOT output is not deterministic")
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scopeModuleUpload lets a token publish modules through /api/upload
+const scopeModuleUpload = "module:upload"
+
+// maxTokensPerUser caps how many live tokens a user can hold
+const maxTokensPerUser = 10
+
+// RequireAuthOrToken accepts either a logged-in session or an
+// "Authorization: Bearer <token>" header carrying scope. Token requests run as
+// the token's owner, with the same rights as their web session.
+func (h *Handlers) RequireAuthOrToken(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			h.RequireAuth(next)(w, r)
+			return
+		}
+
+		username, role, scopes, err := h.lookupAPIToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "Invalid, expired or revoked API token"})
+			return
+		}
+		if err != nil {
+			log.Printf("Database error checking API token: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+			return
+		}
+		if !hasScope(scopes, scope) {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{"success": false, "error": "API token does not allow " + scope})
+			return
+		}
+
+		next(w, h.auth.WithAPIUser(r, username, role == "admin"))
+	}
+}
+
+// lookupAPIToken returns the owner, their role and the token's scopes for a
+// live token, recording that it was used
+func (h *Handlers) lookupAPIToken(token string) (string, string, string, error) {
+	keyHash := hashAPIKey(token)
+	var username, role, scopes string
+	err := h.db.QueryRow(`
+		SELECT u.username, u.role, ak.scopes
+		FROM api_keys ak
+		JOIN users u ON ak.user_id = u.id
+		WHERE ak.key_hash = ?
+		  AND ak.revoked = 0
+		  AND (ak.expires_at IS NULL OR ak.expires_at > CURRENT_TIMESTAMP)
+	`, keyHash).Scan(&username, &role, &scopes)
+	if err != nil {
+		return "", "", "", err
+	}
+	if _, err := h.db.Exec("UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE key_hash = ?", keyHash); err != nil {
+		log.Printf("Warning: failed to record API token use: %v", err)
+	}
+	return username, role, scopes, nil
+}
+
+func hasScope(scopesJSON, scope string) bool {
+	var scopes []string
+	if err := json.Unmarshal([]byte(scopesJSON), &scopes); err != nil {
+		return false
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APITokens handles /api/tokens for the logged-in user
+// GET lists their tokens, POST (name, expires_days) creates one for module
+// uploads and returns it once, POST with action=revoke and id revokes one.
+func (h *Handlers) APITokens(w http.ResponseWriter, r *http.Request) {
+	var userID int64
+	err := h.db.QueryRow("SELECT id FROM users WHERE username = ?", h.auth.GetUsername(r)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"success": false, "error": "API tokens need a registry user account"})
+		return
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.listAPITokens(w, userID)
+	case http.MethodPost:
+		if r.FormValue("action") == "revoke" {
+			h.revokeAPIToken(w, r, userID)
+			return
+		}
+		h.createAPIToken(w, r, userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handlers) listAPITokens(w http.ResponseWriter, userID int64) {
+	rows, err := h.db.Query(`
+		SELECT id, name, scopes, created_at, COALESCE(expires_at, ''), COALESCE(last_used_at, ''), revoked
+		FROM api_keys WHERE user_id = ? ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+	defer rows.Close()
+
+	tokens := []map[string]interface{}{}
+	for rows.Next() {
+		var id int64
+		var name, scopes, createdAt, expiresAt, lastUsedAt string
+		var revoked bool
+		if err := rows.Scan(&id, &name, &scopes, &createdAt, &expiresAt, &lastUsedAt, &revoked); err != nil {
+			log.Printf("Error scanning API token: %v", err)
+			continue
+		}
+		var scopeList []string
+		_ = json.Unmarshal([]byte(scopes), &scopeList)
+		tokens = append(tokens, map[string]interface{}{
+			"id":           id,
+			"name":         name,
+			"scopes":       scopeList,
+			"created_at":   createdAt,
+			"expires_at":   expiresAt,
+			"last_used_at": lastUsedAt,
+			"revoked":      revoked,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+func (h *Handlers) createAPIToken(w http.ResponseWriter, r *http.Request, userID int64) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || len(name) > 64 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Token name is required (max 64 characters)"})
+		return
+	}
+
+	var live int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revoked = 0", userID).Scan(&live); err != nil {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+	if live >= maxTokensPerUser {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "Too many active tokens; revoke one first"})
+		return
+	}
+
+	// Stored in SQLite's CURRENT_TIMESTAMP format so the expiry check compares like with like
+	var expiresAt sql.NullString
+	if days, err := strconv.Atoi(r.FormValue("expires_days")); err == nil && days > 0 {
+		expiresAt.Valid = true
+		expiresAt.String = time.Now().UTC().AddDate(0, 0, days).Format("2006-01-02 15:04:05")
+	}
+
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		log.Printf("Error generating random key: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to generate token"})
+		return
+	}
+	token := "clipilot_" + base64.RawURLEncoding.EncodeToString(keyBytes)
+	scopes, _ := json.Marshal([]string{scopeModuleUpload})
+
+	if _, err := h.db.Exec(`
+		INSERT INTO api_keys (user_id, key_hash, name, scopes, expires_at, revoked, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, CURRENT_TIMESTAMP)
+	`, userID, hashAPIKey(token), name, string(scopes), expiresAt); err != nil {
+		log.Printf("Error inserting API token: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to create token"})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"token":   token,
+		"message": "Copy this token now - it won't be shown again",
+	})
+}
+
+func (h *Handlers) revokeAPIToken(w http.ResponseWriter, r *http.Request, userID int64) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Invalid token ID"})
+		return
+	}
+	result, err := h.db.Exec("UPDATE api_keys SET revoked = 1 WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		log.Printf("Error revoking API token: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to revoke token"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "Token not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "message": "Token revoked"})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/themobileprof/clipilot/server/auth"
+)
+
+func TestAPITokenAuthenticatesUploads(t *testing.T) {
+	db := openTestDB(t)
+	result, err := db.Exec(`INSERT INTO users (username, email, password_hash, role) VALUES ('bob', 'bob@example.com', 'x', 'user')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, _ := result.LastInsertId()
+	h := &Handlers{db: db, auth: auth.NewManager("admin", "secret")}
+
+	form := url.Values{"name": {"ci"}, "expires_days": {"30"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.createAPIToken(w, req, userID)
+	var created struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil || created.Token == "" {
+		t.Fatalf("no token created: status %d, %v", w.Code, err)
+	}
+
+	var gotUser string
+	protected := h.RequireAuthOrToken(scopeModuleUpload, func(w http.ResponseWriter, r *http.Request) {
+		gotUser = h.auth.GetUsername(r)
+	})
+
+	req = httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+	w = httptest.NewRecorder()
+	protected(w, req)
+	if gotUser != "bob" {
+		t.Fatalf("upload ran as %q, want bob (status %d)", gotUser, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.RequireAuthOrToken("install:upload", func(http.ResponseWriter, *http.Request) {})(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d for a token without the scope, want 403", w.Code)
+	}
+
+	if _, err := db.Exec("UPDATE api_keys SET revoked = 1"); err != nil {
+		t.Fatal(err)
+	}
+	gotUser = ""
+	w = httptest.NewRecorder()
+	protected(w, req)
+	if w.Code != http.StatusUnauthorized || gotUser != "" {
+		t.Fatalf("revoked token: status %d, user %q", w.Code, gotUser)
+	}
+}
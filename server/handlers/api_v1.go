@@ -12,6 +12,11 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	"github.com/themobileprof/clipilot/server/scanner"
 )
 
 // APIv1ListModules handles GET /api/v1/modules with filtering, pagination, and sorting
@@ -22,26 +27,49 @@ func (h *Handlers) APIv1ListModules(w http.ResponseWriter, r *http.Request) {
 	updatedSince := query.Get("updated_since")
 	platform := query.Get("platform")
 	search := query.Get("search")
+	if search == "" {
+		search = query.Get("q")
+	}
+	if tags == "" {
+		tags = query.Get("tag")
+	}
+	author := query.Get("author")
 	sortBy := query.Get("sort_by")
+	order := query.Get("order")
+	// sort=downloads|recent|name is shorthand for sort_by + order
+	switch query.Get("sort") {
+	case "downloads":
+		sortBy, order = "downloads", "desc"
+	case "recent":
+		sortBy, order = "uploaded_at", "desc"
+	case "name":
+		sortBy, order = "name", "asc"
+	}
 	if sortBy == "" {
 		sortBy = "name"
 	}
-	order := query.Get("order")
 	if order == "" {
 		order = "asc"
 	}
 
 	limit, _ := strconv.Atoi(query.Get("limit"))
+	if perPage, err := strconv.Atoi(query.Get("per_page")); err == nil {
+		limit = perPage
+	}
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
 	offset, _ := strconv.Atoi(query.Get("offset"))
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page > 0 {
+		offset = (page - 1) * limit
+	}
 	if offset < 0 {
 		offset = 0
 	}
 
 	// Build SQL query with filters
-	sqlQuery := "SELECT id, name, version, description, author, COALESCE(tags, '[]'), uploaded_at, uploaded_by, downloads FROM modules WHERE 1=1"
+	sqlQuery := "SELECT id, name, version, description, author, COALESCE(tags, '[]'), uploaded_at, uploaded_by, downloads, COALESCE(risk_level, 'low') FROM modules WHERE hidden = 0 AND status = 'approved'"
 	args := []interface{}{}
 
 	// Apply filters
@@ -72,8 +100,13 @@ func (h *Handlers) APIv1ListModules(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if search != "" {
-		sqlQuery += " AND (name LIKE '%' || ? || '%' OR description LIKE '%' || ? || '%')"
-		args = append(args, search, search)
+		sqlQuery += " AND (name LIKE '%' || ? || '%' OR description LIKE '%' || ? || '%' OR tags LIKE '%' || ? || '%')"
+		args = append(args, search, search, search)
+	}
+
+	if author != "" {
+		sqlQuery += " AND (author = ? OR uploaded_by = ?)"
+		args = append(args, author, author)
 	}
 
 	// Get total count before pagination
@@ -109,11 +142,11 @@ func (h *Handlers) APIv1ListModules(w http.ResponseWriter, r *http.Request) {
 	modules := []map[string]interface{}{}
 	for rows.Next() {
 		var id int64
-		var name, version, description, author, tagsJSON, uploadedBy string
+		var name, version, description, author, tagsJSON, uploadedBy, riskLevel string
 		var uploadedAt time.Time
 		var downloads int
 
-		if err := rows.Scan(&id, &name, &version, &description, &author, &tagsJSON, &uploadedAt, &uploadedBy, &downloads); err != nil {
+		if err := rows.Scan(&id, &name, &version, &description, &author, &tagsJSON, &uploadedAt, &uploadedBy, &downloads, &riskLevel); err != nil {
 			log.Printf("Scan error: %v", err)
 			continue
 		}
@@ -132,15 +165,18 @@ func (h *Handlers) APIv1ListModules(w http.ResponseWriter, r *http.Request) {
 			"uploaded_by":    uploadedBy,
 			"uploaded_at":    uploadedAt.Format(time.RFC3339),
 			"updated_at":     uploadedAt.Format(time.RFC3339),
+			"risk_level":     riskLevel,
 		}
 
 		modules = append(modules, module)
 	}
 
-	// Generate ETag
-	etag := fmt.Sprintf(`"v1-modules-%d"`, time.Now().Unix()/300) // Cache for 5 minutes
+	// Generate ETag, per query so one page's tag never validates another's
+	querySum := sha256.Sum256([]byte(r.URL.RawQuery))
+	etag := fmt.Sprintf(`"v1-modules-%d-%x"`, time.Now().Unix()/300, querySum[:8]) // Cache for 5 minutes
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	w.Header().Set("ETag", etag)
 	w.Header().Set("Last-Modified", time.Now().Format(http.TimeFormat))
 
@@ -151,10 +187,12 @@ func (h *Handlers) APIv1ListModules(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := map[string]interface{}{
-		"modules": modules,
-		"total":   total,
-		"limit":   limit,
-		"offset":  offset,
+		"modules":  modules,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"page":     offset/limit + 1,
+		"per_page": limit,
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -174,16 +212,16 @@ func (h *Handlers) APIv1GetModule(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var id int64
-	var name, version, description, author, tagsJSON, uploadedBy, filePath string
+	var name, version, description, author, tagsJSON, uploadedBy, filePath, riskLevel, riskJSON string
 	var uploadedAt time.Time
 	var downloads int
 
 	err := h.db.QueryRow(`
 		SELECT id, name, version, description, author, COALESCE(tags, '[]'), 
-		       uploaded_at, uploaded_by, file_path, downloads
-		FROM modules WHERE name = ?
+		       uploaded_at, uploaded_by, file_path, downloads, COALESCE(risk_level, 'low'), COALESCE(risk_report, '')
+		FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved'
 		ORDER BY uploaded_at DESC LIMIT 1
-	`, moduleID).Scan(&id, &name, &version, &description, &author, &tagsJSON, &uploadedAt, &uploadedBy, &filePath, &downloads)
+	`, moduleID).Scan(&id, &name, &version, &description, &author, &tagsJSON, &uploadedAt, &uploadedBy, &filePath, &downloads, &riskLevel, &riskJSON)
 
 	if err == sql.ErrNoRows {
 		w.WriteHeader(http.StatusNotFound)
@@ -207,12 +245,20 @@ func (h *Handlers) APIv1GetModule(w http.ResponseWriter, r *http.Request) {
 	// Parse tags
 	var tagsList []string
 	_ = json.Unmarshal([]byte(tagsJSON), &tagsList)
+	riskReport := scanner.Report{Level: riskLevel, Findings: []scanner.Finding{}}
+	_ = json.Unmarshal([]byte(riskJSON), &riskReport)
 
 	// Calculate checksum
 	checksum := ""
+	hasUninstall := false
+	privileged := []string{}
 	if content, err := os.ReadFile(filePath); err == nil {
-		hash := sha256.Sum256(content)
-		checksum = fmt.Sprintf("%x", hash)
+		checksum = checksumOf(content)
+		var parsed models.Module
+		if err := yaml.Unmarshal(content, &parsed); err == nil {
+			hasUninstall = hasUninstallFlow(&parsed)
+			privileged = rootSteps(&parsed)
+		}
 	}
 
 	module := map[string]interface{}{
@@ -226,21 +272,25 @@ func (h *Handlers) APIv1GetModule(w http.ResponseWriter, r *http.Request) {
 		"uploaded_at":     uploadedAt.Format(time.RFC3339),
 		"updated_at":      uploadedAt.Format(time.RFC3339),
 		"checksum_sha256": checksum,
+		"has_uninstall":   hasUninstall,
+		"requires_root":   len(privileged) > 0,
+		"root_steps":      privileged,
+		"risk_level":      riskLevel,
+		"risk_report":     riskReport,
 	}
 
-	etag := fmt.Sprintf(`"%s"`, checksum)
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("ETag", etag)
-	w.Header().Set("Last-Modified", uploadedAt.Format(http.TimeFormat))
-
-	if r.Header.Get("If-None-Match") == etag {
-		w.WriteHeader(http.StatusNotModified)
-		return
+	if versions, err := h.moduleVersions(name); err == nil {
+		module["versions"] = versions
+	} else {
+		log.Printf("Failed to load module versions: %v", err)
 	}
 
-	if err := json.NewEncoder(w).Encode(module); err != nil {
-		log.Printf("Failed to encode module: %v", err)
+	if stats, err := getModuleRunStats(h.db, name); err == nil && stats.Runs > 0 {
+		module["stats"] = stats
 	}
+
+	w.Header().Set("Last-Modified", uploadedAt.Format(http.TimeFormat))
+	writeCachedJSON(w, r, module)
 }
 
 // APIv1DownloadModule handles GET /api/v1/modules/:id/download
@@ -249,14 +299,17 @@ func (h *Handlers) APIv1DownloadModule(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
 	moduleID := strings.Split(path, "/")[0]
 
-	var filePath, name string
+	var filePath, name, version, riskLevel string
 	var uploadedAt time.Time
 
-	err := h.db.QueryRow(`
-		SELECT file_path, name, uploaded_at
-		FROM modules WHERE name = ?
-		ORDER BY uploaded_at DESC LIMIT 1
-	`, moduleID).Scan(&filePath, &name, &uploadedAt)
+	// ?version= downloads a specific (e.g. pinned) version instead of the latest
+	query := "SELECT file_path, name, version, uploaded_at, COALESCE(risk_level, 'low') FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved'"
+	args := []interface{}{moduleID}
+	if v := r.URL.Query().Get("version"); v != "" {
+		query += " AND version = ?"
+		args = append(args, v)
+	}
+	err := h.db.QueryRow(query+" ORDER BY uploaded_at DESC LIMIT 1", args...).Scan(&filePath, &name, &version, &uploadedAt, &riskLevel)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, "Module not found", http.StatusNotFound)
@@ -278,12 +331,14 @@ func (h *Handlers) APIv1DownloadModule(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Calculate checksum for ETag
-	hash := sha256.Sum256(content)
-	checksum := fmt.Sprintf("%x", hash)
+	checksum := checksumOf(content)
 	etag := fmt.Sprintf(`"%s"`, checksum)
 
 	w.Header().Set("Content-Type", "application/x-yaml")
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.yaml"`, name))
+	w.Header().Set("X-Module-Version", version)
+	w.Header().Set("X-Checksum-SHA256", checksum)
+	w.Header().Set("X-Risk-Level", riskLevel)
 	w.Header().Set("ETag", etag)
 	w.Header().Set("Last-Modified", uploadedAt.Format(http.TimeFormat))
 
@@ -300,126 +355,176 @@ func (h *Handlers) APIv1DownloadModule(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Failed to increment download counter: %v", err)
 		}
 	}()
+	h.recordDownload(r, name, version)
 
 	if _, err := w.Write(content); err != nil {
 		log.Printf("Failed to write content: %v", err)
 	}
 }
 
-// APIv1ChangedModules handles GET /api/v1/modules/changed for delta sync
+// maxChangesPage caps how many modules one delta sync response lists
+const maxChangesPage = 500
+
+// APIv1ChangedModules handles GET /api/v1/modules/changed (alias /changes) for delta sync.
+// Changes come from the module_changes log, so every upload, approval, hide
+// and delete shows up exactly once per cursor; modules with no visible,
+// approved version left are returned as "removed" tombstones.
 func (h *Handlers) APIv1ChangedModules(w http.ResponseWriter, r *http.Request) {
+	// The cursor is ?after= (a change id from next_after), ?since= (or
+	// ?updated_after=), or an If-Modified-Since header, in which case an
+	// empty result is a 304. Timestamps have second precision, so since is
+	// inclusive and may repeat changes from the boundary second.
+	where := "c.id > ?"
+	args := []interface{}{int64(0)}
+	conditional := false
 	since := r.URL.Query().Get("since")
 	if since == "" {
-		http.Error(w, `{"error":"Missing 'since' parameter"}`, http.StatusBadRequest)
-		return
+		since = r.URL.Query().Get("updated_after")
 	}
-
-	// Parse timestamp
-	sinceTime, err := time.Parse(time.RFC3339, since)
-	if err != nil {
-		http.Error(w, `{"error":"Invalid timestamp format, use RFC3339"}`, http.StatusBadRequest)
+	if after := r.URL.Query().Get("after"); after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, `{"error":"Invalid after cursor"}`, http.StatusBadRequest)
+			return
+		}
+		args[0] = id
+	} else if since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, `{"error":"Invalid timestamp format, use RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		where += " AND c.changed_at >= ?"
+		args = append(args, sinceTime.UTC().Format("2006-01-02 15:04:05"))
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		sinceTime, err := http.ParseTime(ims)
+		if err != nil {
+			http.Error(w, `{"error":"Invalid If-Modified-Since header"}`, http.StatusBadRequest)
+			return
+		}
+		where += " AND c.changed_at > ?"
+		args = append(args, sinceTime.UTC().Format("2006-01-02 15:04:05"))
+		conditional = true
+	} else {
+		http.Error(w, `{"error":"Missing 'since' or 'after' parameter"}`, http.StatusBadRequest)
 		return
 	}
 
+	// One row per module, in the order of its last change, plus whether the
+	// window holds its first ever change
 	rows, err := h.db.Query(`
-		SELECT name, version, uploaded_at, file_path
-		FROM modules WHERE uploaded_at > ?
-		ORDER BY uploaded_at ASC
-	`, sinceTime)
-
+		SELECT c.module_name, MAX(c.id),
+		       MIN(c.id) = (SELECT MIN(p.id) FROM module_changes p WHERE p.module_name = c.module_name)
+		FROM module_changes c
+		WHERE `+where+`
+		GROUP BY c.module_name
+		ORDER BY MAX(c.id)
+		LIMIT ?
+	`, append(args, maxChangesPage+1)...)
 	if err != nil {
 		log.Printf("Database error: %v", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	changedModules := []map[string]interface{}{}
+	type change struct {
+		name  string
+		id    int64
+		added bool
+	}
+	var changes []change
 	for rows.Next() {
-		var name, version, filePath string
-		var uploadedAt time.Time
-
-		if err := rows.Scan(&name, &version, &uploadedAt, &filePath); err != nil {
+		var c change
+		if err := rows.Scan(&c.name, &c.id, &c.added); err != nil {
 			log.Printf("Scan error: %v", err)
 			continue
 		}
-
-		// Calculate checksum
-		checksum := ""
-		if content, err := os.ReadFile(filePath); err == nil {
-			hash := sha256.Sum256(content)
-			checksum = fmt.Sprintf("%x", hash)
-		}
-
-		module := map[string]interface{}{
-			"id":              name,
-			"version":         version,
-			"checksum_sha256": checksum,
-			"updated_at":      uploadedAt.Format(time.RFC3339),
-			"change_type":     "updated", // Could be "added" or "updated"
-		}
-
-		changedModules = append(changedModules, module)
+		changes = append(changes, c)
 	}
-
-	response := map[string]interface{}{
-		"changed_modules": changedModules,
-		"sync_timestamp":  time.Now().Format(time.RFC3339),
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode changed modules response: %v", err)
+	hasMore := len(changes) > maxChangesPage
+	if hasMore {
+		changes = changes[:maxChangesPage]
 	}
-}
-
-// APIv1ModuleDependencies handles GET /api/v1/modules/:id/dependencies
-func (h *Handlers) APIv1ModuleDependencies(w http.ResponseWriter, r *http.Request) {
-	// Extract module ID from path: /api/v1/modules/{id}/dependencies
-	path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
-	moduleID := strings.Split(path, "/")[0]
 
-	// This is a simplified implementation
-	// A full implementation would parse the YAML and recursively resolve dependencies
-	var filePath string
-	err := h.db.QueryRow(`
-		SELECT file_path FROM modules WHERE name = ?
-		ORDER BY uploaded_at DESC LIMIT 1
-	`, moduleID).Scan(&filePath)
+	changedModules := []map[string]interface{}{}
+	nextAfter := args[0].(int64)
+	var cursor time.Time
+	for _, c := range changes {
+		var changedAt time.Time
+		if err := h.db.QueryRow("SELECT changed_at FROM module_changes WHERE id = ?", c.id).Scan(&changedAt); err != nil {
+			log.Printf("Failed to load change %d: %v", c.id, err)
+			continue
+		}
+		nextAfter = c.id
+		if changedAt.After(cursor) {
+			cursor = changedAt.UTC()
+		}
 
-	if err == sql.ErrNoRows {
-		http.Error(w, "Module not found", http.StatusNotFound)
-		return
-	}
+		var version, filePath, checksum string
+		err := h.db.QueryRow(`
+			SELECT version, file_path, COALESCE(checksum, '')
+			FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved'
+			ORDER BY uploaded_at DESC, id DESC LIMIT 1
+		`, c.name).Scan(&version, &filePath, &checksum)
+		if err == sql.ErrNoRows {
+			changedModules = append(changedModules, map[string]interface{}{
+				"id":          c.name,
+				"updated_at":  changedAt.UTC().Format(time.RFC3339),
+				"change_type": "removed",
+			})
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to load module %s: %v", c.name, err)
+			continue
+		}
 
-	if err != nil {
-		log.Printf("Database error: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		if checksum == "" {
+			if content, err := os.ReadFile(filePath); err == nil {
+				checksum = checksumOf(content)
+			}
+		}
+		changeType := "updated"
+		if c.added {
+			changeType = "added"
+		}
+		changedModules = append(changedModules, map[string]interface{}{
+			"id":              c.name,
+			"version":         version,
+			"checksum_sha256": checksum,
+			"updated_at":      changedAt.UTC().Format(time.RFC3339),
+			"change_type":     changeType,
+		})
 	}
 
-	// Read and parse YAML to extract requires field
-	_, err = os.ReadFile(filePath)
-	if err != nil {
-		log.Printf("File read error: %v", err)
-		http.Error(w, "Module file not found", http.StatusNotFound)
+	if conditional && len(changedModules) == 0 {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	// Simple regex to extract requires field (basic implementation)
-	// A production version should use proper YAML parsing
-	// TODO: Implement proper dependency resolution
-	dependencies := []interface{}{}
-
 	response := map[string]interface{}{
-		"module_id":     moduleID,
-		"dependencies":  dependencies,
-		"install_order": []string{moduleID}, // Simplified
+		"changed_modules": changedModules,
+		"sync_timestamp":  time.Now().UTC().Format(time.RFC3339),
+		// next_after is the last change returned; pass it as after next time
+		"next_after": nextAfter,
+		"has_more":   hasMore,
+	}
+	if !cursor.IsZero() {
+		// cursor is the newest updated_at returned, for clients still using since
+		response["cursor"] = cursor.Format(time.RFC3339)
+		w.Header().Set("Last-Modified", cursor.Format(http.TimeFormat))
+	} else if since != "" {
+		response["cursor"] = since
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode dependencies response: %v", err)
+		log.Printf("Failed to encode changed modules response: %v", err)
 	}
 }
 
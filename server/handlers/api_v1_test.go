@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIv1ChangedModules(t *testing.T) {
+	db := openTestDB(t)
+	h := &Handlers{db: db}
+
+	type changes struct {
+		Modules []struct {
+			ID         string `json:"id"`
+			Version    string `json:"version"`
+			ChangeType string `json:"change_type"`
+		} `json:"changed_modules"`
+		NextAfter int64 `json:"next_after"`
+	}
+	get := func(query string, header http.Header) (int, changes) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/modules/changes?"+query, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		h.APIv1ChangedModules(w, r)
+		var c changes
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, c
+	}
+	summary := func(c changes) string {
+		s := ""
+		for _, m := range c.Modules {
+			s += fmt.Sprintf("%s@%s:%s ", m.ID, m.Version, m.ChangeType)
+		}
+		return s
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, checksum) VALUES
+			('alpha', '1.0.0', 'bob', '/dev/null', 'a1'),
+			('beta', '1.0.0', 'bob', '/dev/null', 'b1')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	code, first := get("after=0", nil)
+	if code != http.StatusOK || summary(first) != "alpha@1.0.0:added beta@1.0.0:added " {
+		t.Fatalf("first sync = %d %q", code, summary(first))
+	}
+
+	// Everything below happens within the same second as the first sync; the
+	// pending upload is not public and must not show up
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path, checksum) VALUES ('alpha', '1.1.0', 'bob', '/dev/null', 'a2')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path, status) VALUES ('gamma', '1.0.0', 'bob', '/dev/null', 'pending')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE modules SET hidden = 1 WHERE name = 'beta'"); err != nil {
+		t.Fatal(err)
+	}
+	_, second := get(fmt.Sprintf("after=%d", first.NextAfter), nil)
+	if got := summary(second); got != "alpha@1.1.0:updated beta@:removed " {
+		t.Fatalf("second sync = %q", got)
+	}
+
+	if _, next := get(fmt.Sprintf("after=%d", second.NextAfter), nil); len(next.Modules) != 0 {
+		t.Fatalf("sync after the last change = %q, want nothing", summary(next))
+	}
+	since := time.Now().UTC().Add(-time.Second).Format(time.RFC3339)
+	if _, c := get("since="+since, nil); len(c.Modules) != 2 {
+		t.Fatalf("since sync = %q, want both changed modules", summary(c))
+	}
+	future := http.Header{"If-Modified-Since": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}
+	if code, _ := get("", future); code != http.StatusNotModified {
+		t.Fatalf("conditional sync = %d, want 304", code)
+	}
+	if code, _ := get("after=x", nil); code != http.StatusBadRequest {
+		t.Fatalf("bad cursor = %d, want 400", code)
+	}
+}
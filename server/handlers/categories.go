@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/themobileprof/clipilot/server/catalog"
+)
+
+// defaultCategorySynonyms seed the taxonomy on first start so common
+// spellings from AI output and module tags resolve to catalog categories
+var defaultCategorySynonyms = map[string]string{
+	"network":    "networking",
+	"net":        "networking",
+	"files":      "file-management",
+	"file":       "file-management",
+	"filesystem": "file-management",
+	"db":         "database",
+	"databases":  "database",
+	"dev":        "development",
+	"devtools":   "development",
+	"editors":    "editor",
+	"container":  "containers",
+	"docker":     "containers",
+	"sys":        "system",
+	"sysadmin":   "system",
+	"shell":      "terminal",
+}
+
+var validCategoryName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Category is a canonical category with the synonyms that resolve to it
+type Category struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Synonyms    []string `json:"synonyms"`
+	Commands    int      `json:"commands"`
+	Modules     int      `json:"modules"`
+}
+
+// seedCategories fills an empty taxonomy from the catalog and default synonyms.
+// Once admins have edited the taxonomy it is left alone.
+func seedCategories(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// "remote" labels Gemini results that are not in the catalog
+	for _, name := range append(catalog.Categories(), "remote") {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO categories (name) VALUES (?)", name); err != nil {
+			return err
+		}
+	}
+	for synonym, name := range defaultCategorySynonyms {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO category_synonyms (synonym, category) VALUES (?, ?)", synonym, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// loadCategoryIndex maps every canonical name and synonym to its canonical category
+func loadCategoryIndex(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query(`
+		SELECT name, name FROM categories
+		UNION ALL
+		SELECT synonym, category FROM category_synonyms
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := map[string]string{}
+	for rows.Next() {
+		var key, name string
+		if err := rows.Scan(&key, &name); err != nil {
+			return nil, err
+		}
+		index[key] = name
+	}
+	return index, rows.Err()
+}
+
+// canonicalCategory resolves a category through the index; unknown
+// categories are returned lowercased so they still group consistently
+func canonicalCategory(index map[string]string, category string) string {
+	key := strings.ToLower(strings.TrimSpace(category))
+	if name, ok := index[key]; ok {
+		return name
+	}
+	return key
+}
+
+// canonicalizeTags rewrites tags that name a known category to its canonical
+// form and drops duplicates; other tags are kept as written
+func canonicalizeTags(index map[string]string, tags []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if name, ok := index[strings.ToLower(strings.TrimSpace(tag))]; ok {
+			tag = name
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// listCategories returns the taxonomy with synonym and usage counts
+func (h *Handlers) listCategories() ([]*Category, error) {
+	rows, err := h.db.Query(`
+		SELECT c.name, c.description, COALESCE(s.synonym, '')
+		FROM categories c
+		LEFT JOIN category_synonyms s ON s.category = c.name
+		ORDER BY c.name, s.synonym
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	byName := map[string]*Category{}
+	for rows.Next() {
+		var name, description, synonym string
+		if err := rows.Scan(&name, &description, &synonym); err != nil {
+			return nil, err
+		}
+		c, ok := byName[name]
+		if !ok {
+			c = &Category{Name: name, Description: description, Synonyms: []string{}}
+			byName[name] = c
+			categories = append(categories, c)
+		}
+		if synonym != "" {
+			c.Synonyms = append(c.Synonyms, synonym)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	index, err := loadCategoryIndex(h.db)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range catalog.All() {
+		if c, ok := byName[canonicalCategory(index, entry.Category)]; ok {
+			c.Commands++
+		}
+	}
+
+	tagRows, err := h.db.Query("SELECT COALESCE(tags, '[]') FROM modules WHERE hidden = 0 AND status = 'approved'")
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var tagsJSON string
+		var tags []string
+		if err := tagRows.Scan(&tagsJSON); err != nil || json.Unmarshal([]byte(tagsJSON), &tags) != nil {
+			continue
+		}
+		// Count each module once per category, whichever synonym it was tagged with
+		counted := map[string]bool{}
+		for _, tag := range tags {
+			name := canonicalCategory(index, tag)
+			if c, ok := byName[name]; ok && !counted[name] {
+				counted[name] = true
+				c.Modules++
+			}
+		}
+	}
+	return categories, tagRows.Err()
+}
+
+// APICategories handles GET /api/categories
+// Clio uses it to keep its category boosts in line with the registry taxonomy.
+func (h *Handlers) APICategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	categories, err := h.listCategories()
+	if err != nil {
+		log.Printf("Failed to list categories: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"categories": categories,
+	})
+}
+
+// AdminCategoriesPage shows the taxonomy with forms to add, rename and merge categories
+func (h *Handlers) AdminCategoriesPage(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.IsAdmin(r) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	categories, err := h.listCategories()
+	if err != nil {
+		log.Printf("Failed to list categories: %v", err)
+		http.Error(w, "Failed to load categories", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":      "Categories",
+		"LoggedIn":   true,
+		"Session":    h.auth.GetSession(r),
+		"Categories": categories,
+	}
+	if msg := r.URL.Query().Get("success"); msg != "" {
+		data["Success"] = msg
+	}
+	if msg := r.URL.Query().Get("error"); msg != "" {
+		data["Error"] = msg
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "categories-admin.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// AdminCategoryAction handles POST /admin/categories/action (form: action, name, target, description).
+// "add" creates a category, "synonym" adds name as a synonym of target,
+// "rename" and "merge" fold name into target and rewrite module tags.
+func (h *Handlers) AdminCategoryAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.auth.IsAdmin(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	action := r.FormValue("action")
+	name := strings.ToLower(strings.TrimSpace(r.FormValue("name")))
+	target := strings.ToLower(strings.TrimSpace(r.FormValue("target")))
+	if !validCategoryName.MatchString(name) || (action != "add" && !validCategoryName.MatchString(target)) {
+		redirectCategories(w, r, "error", "Category names must be lowercase letters, digits and dashes")
+		return
+	}
+
+	var message string
+	var err error
+	switch action {
+	case "add":
+		_, err = h.db.Exec("INSERT INTO categories (name, description) VALUES (?, ?)", name, strings.TrimSpace(r.FormValue("description")))
+		message = fmt.Sprintf("Added category %s", name)
+	case "synonym":
+		var result sql.Result
+		result, err = h.db.Exec("INSERT OR REPLACE INTO category_synonyms (synonym, category) SELECT ?, name FROM categories WHERE name = ?", name, target)
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				err = fmt.Errorf("category %s does not exist", target)
+			}
+		}
+		message = fmt.Sprintf("%s now resolves to %s", name, target)
+	case "rename":
+		err = h.mergeCategory(name, target, true)
+		message = fmt.Sprintf("Renamed %s to %s", name, target)
+	case "merge":
+		err = h.mergeCategory(name, target, false)
+		message = fmt.Sprintf("Merged %s into %s", name, target)
+	default:
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Category %s failed: %v", action, err)
+		redirectCategories(w, r, "error", fmt.Sprintf("Could not %s %s: %v", action, name, err))
+		return
+	}
+
+	log.Printf("Category %s: %s -> %s by %s", action, name, target, h.auth.GetUsername(r))
+	redirectCategories(w, r, "success", message)
+}
+
+// mergeCategory folds from into to: synonyms move across, from becomes a
+// synonym of to and module tags are rewritten. When create is set (rename)
+// the target is created with from's description if it does not exist yet.
+func (h *Handlers) mergeCategory(from, to string, create bool) error {
+	if from == to {
+		return fmt.Errorf("source and target are the same")
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var description string
+	if err := tx.QueryRow("SELECT description FROM categories WHERE name = ?", from).Scan(&description); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("category %s does not exist", from)
+		}
+		return err
+	}
+
+	if create {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO categories (name, description) VALUES (?, ?)", to, description); err != nil {
+			return err
+		}
+	} else {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE name = ?)", to).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("category %s does not exist", to)
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE category_synonyms SET category = ? WHERE category = ?", to, from); err != nil {
+		return err
+	}
+	// A synonym spelled like the target would now point at itself
+	if _, err := tx.Exec("DELETE FROM category_synonyms WHERE synonym = ?", to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM categories WHERE name = ?", from); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT OR REPLACE INTO category_synonyms (synonym, category) VALUES (?, ?)", from, to); err != nil {
+		return err
+	}
+
+	// Rewrite stored module tags; uploaded YAML files keep whatever the author wrote
+	rows, err := tx.Query("SELECT id, tags FROM modules WHERE tags LIKE ?", `%"`+from+`"%`)
+	if err != nil {
+		return err
+	}
+	updates := map[int]string{}
+	for rows.Next() {
+		var id int
+		var tagsJSON string
+		var tags []string
+		if err := rows.Scan(&id, &tagsJSON); err != nil || json.Unmarshal([]byte(tagsJSON), &tags) != nil {
+			continue
+		}
+		rewritten, _ := json.Marshal(canonicalizeTags(map[string]string{from: to}, tags))
+		updates[id] = string(rewritten)
+	}
+	rows.Close()
+	for id, tagsJSON := range updates {
+		if _, err := tx.Exec("UPDATE modules SET tags = ? WHERE id = ?", tagsJSON, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func redirectCategories(w http.ResponseWriter, r *http.Request, key, message string) {
+	http.Redirect(w, r, "/admin/categories?"+key+"="+url.QueryEscape(message), http.StatusSeeOther)
+}
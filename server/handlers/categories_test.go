@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/themobileprof/clipilot/server/auth"
+)
+
+func TestCanonicalizeTags(t *testing.T) {
+	index := map[string]string{"networking": "networking", "network": "networking", "db": "database"}
+	got := canonicalizeTags(index, []string{"Network", "networking", "DB", "termux"})
+	want := []string{"networking", "database", "termux"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("canonicalizeTags = %v, want %v", got, want)
+	}
+	if c := canonicalCategory(index, " Unknown "); c != "unknown" {
+		t.Fatalf("canonicalCategory(unknown) = %q", c)
+	}
+}
+
+func TestCategoryAdmin(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO categories (name) VALUES ('containers'), ('docker-tools'), ('system');
+		INSERT INTO category_synonyms (synonym, category) VALUES ('docker', 'containers'), ('containers', 'docker-tools');
+		INSERT INTO modules (name, version, uploaded_by, file_path, tags) VALUES
+			('compose_up', '1.0.0', 'bob', '/dev/null', '["docker"]'),
+			('podman_setup', '1.0.0', 'bob', '/dev/null', '["containers", "docker"]'),
+			('disk_check', '1.0.0', 'bob', '/dev/null', '["system"]');
+	`); err != nil {
+		t.Fatal(err)
+	}
+	am := auth.NewManager("admin", "secret")
+	h := &Handlers{db: db, auth: am}
+	act := func(form url.Values) (string, string) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/admin/categories/action", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.AdminCategoryAction(w, am.WithAPIUser(r, "admin", true))
+		loc, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loc.Query().Get("success"), loc.Query().Get("error")
+	}
+	modules := func() map[string]int {
+		t.Helper()
+		categories, err := h.listCategories()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int{}
+		for _, c := range categories {
+			counts[c.Name] = c.Modules
+		}
+		return counts
+	}
+
+	// Synonym tags count toward their category, once per module
+	if got := modules(); got["containers"] != 2 || got["system"] != 1 {
+		t.Fatalf("module counts = %v, want containers 2 and system 1", got)
+	}
+
+	if _, errMsg := act(url.Values{"action": {"synonym"}, "name": {"k8s"}, "target": {"kubernetes"}}); errMsg == "" {
+		t.Fatal("synonym for an unknown category reported success")
+	}
+	var synonyms int
+	if err := db.QueryRow("SELECT COUNT(*) FROM category_synonyms WHERE synonym = 'k8s'").Scan(&synonyms); err != nil || synonyms != 0 {
+		t.Fatalf("k8s synonyms = %d (err %v), want 0", synonyms, err)
+	}
+	if ok, _ := act(url.Values{"action": {"synonym"}, "name": {"k8s"}, "target": {"containers"}}); ok != "k8s now resolves to containers" {
+		t.Fatalf("synonym success = %q", ok)
+	}
+
+	// Merging docker-tools into containers must not leave containers -> containers behind
+	if _, errMsg := act(url.Values{"action": {"merge"}, "name": {"docker-tools"}, "target": {"containers"}}); errMsg != "" {
+		t.Fatalf("merge failed: %s", errMsg)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM category_synonyms WHERE synonym = category").Scan(&synonyms); err != nil || synonyms != 0 {
+		t.Fatalf("%d self-referencing synonyms after merge (err %v), want 0", synonyms, err)
+	}
+	index, err := loadCategoryIndex(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := canonicalCategory(index, "docker-tools"); got != "containers" {
+		t.Fatalf("docker-tools resolves to %q after merge, want containers", got)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIClientCheck handles GET /api/client/check?version=x.y.z
+// Clio calls it during sync. Clients below MinClientVersion should warn and
+// refuse destructive operations until upgraded.
+func (h *Handlers) APIClientCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	current := strings.TrimSpace(r.URL.Query().Get("version"))
+	latest := h.config.LatestClientVersion
+	if latest == "" {
+		// The active install script is published by Clio's release pipeline
+		err := h.db.QueryRow("SELECT version FROM install_scripts WHERE is_active = 1 ORDER BY uploaded_at DESC LIMIT 1").Scan(&latest)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Database error: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	minVersion := h.config.MinClientVersion
+
+	response := map[string]interface{}{
+		"latest_version":   latest,
+		"min_version":      minVersion,
+		"supported":        true,
+		"update_available": false,
+	}
+
+	if current != "" {
+		response["current_version"] = current
+		if minVersion != "" && compareVersions(current, minVersion) < 0 {
+			response["supported"] = false
+			baseURL := h.config.BaseURL
+			if baseURL == "" {
+				baseURL = "https://" + r.Host
+			}
+			response["message"] = fmt.Sprintf("Clio %s is no longer supported (minimum %s). Reinstall with: curl -fsSL %s/clio | bash",
+				current, minVersion, baseURL)
+		}
+		if latest != "" && compareVersions(current, latest) < 0 {
+			response["update_available"] = true
+			if _, ok := response["message"]; !ok {
+				response["message"] = fmt.Sprintf("Clio %s is available (you have %s)", latest, current)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode client check response: %v", err)
+	}
+}
+
+// compareVersions compares dotted versions such as "v1.2.3" or "1.10",
+// returning -1, 0 or 1. Pre-release suffixes ("-rc1") are ignored and
+// missing components count as zero.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for len(pa) < len(pb) {
+		pa = append(pa, 0)
+	}
+	for len(pb) < len(pa) {
+		pb = append(pb, 0)
+	}
+	for i := range pa {
+		if pa[i] < pb[i] {
+			return -1
+		}
+		if pa[i] > pb[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -1,18 +1,19 @@
 package handlers
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/themobileprof/clipilot/internal/llm/gemini"
 	"github.com/themobileprof/clipilot/server/catalog"
 )
 
@@ -21,6 +22,9 @@ type SemanticSearchRequest struct {
 	Query string `json:"query"`
 	OS    string `json:"os,omitempty"`
 	Arch  string `json:"arch,omitempty"`
+	// Installed optionally lists commands the client has, so each candidate
+	// can point at alternatives the user can run right away
+	Installed []string `json:"installed,omitempty"`
 }
 
 // SemanticSearchResponse is returned to Clio (and legacy clients).
@@ -40,12 +44,24 @@ type CommandCandidate struct {
 	UseCases    []string `json:"use_cases"`
 	Keywords    []string `json:"keywords"`
 	Usage       string   `json:"usage,omitempty"`
+	// Alternatives are catalog commands that can stand in for this one
+	// (classics and their modern replacements alike)
+	Alternatives          []string `json:"alternatives,omitempty"`
+	InstalledAlternatives []string `json:"installed_alternatives,omitempty"`
 }
 
 // HandleSemanticSearch serves POST /api/commands/search for the Clio client.
-func HandleSemanticSearch(db *sql.DB, geminiAPIKey string) http.HandlerFunc {
+// Gemini is consulted at most geminiDailyLimit times per UTC day (0 means no
+// limit), with queries redacted and audited before they are sent.
+func HandleSemanticSearch(db *sql.DB, geminiAPIKey string, geminiDailyLimit int) http.HandlerFunc {
 	ensureCacheTable(db)
 
+	var llm *onlineLLM
+	if geminiAPIKey != "" {
+		ensureLLMTables(db)
+		llm = &onlineLLM{client: gemini.New(geminiAPIKey), db: db, dailyLimit: geminiDailyLimit}
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -64,33 +80,47 @@ func HandleSemanticSearch(db *sql.DB, geminiAPIKey string) http.HandlerFunc {
 
 		cacheKey := hashQuery(req.Query, req.OS, req.Arch)
 		if cached, err := getCachedResponse(db, cacheKey); err == nil {
-			writeSearchResponse(w, cached, true, "")
+			writeSearchResponse(w, withAlternatives(cached, req.Installed), true, "")
 			return
 		}
 
-		candidates, source := searchCommands(req.Query, req.OS, geminiAPIKey)
+		candidates, source := searchCommands(req.Query, req.OS, llm)
 		if len(candidates) == 0 {
 			http.Error(w, "No matching commands found", http.StatusNotFound)
 			return
 		}
+		if index, err := loadCategoryIndex(db); err == nil {
+			for i := range candidates {
+				candidates[i].Category = canonicalCategory(index, candidates[i].Category)
+			}
+		}
 
 		go cacheResponse(db, cacheKey, candidates)
 
-		writeSearchResponse(w, candidates, false, source)
+		writeSearchResponse(w, withAlternatives(candidates, req.Installed), false, source)
 	}
 }
 
-func searchCommands(query, os, geminiAPIKey string) ([]CommandCandidate, string) {
-	hits := catalog.Search(query)
+// searchCommands tries the catalog first and falls back to Gemini (when llm is
+// set and today's quota allows)
+func searchCommands(query, os string, llm *onlineLLM) ([]CommandCandidate, string) {
+	hits := catalog.SearchOn(query, os)
 	if len(hits) > 0 && hits[0].Score >= 4.0 {
 		return catalogHitsToCandidates(hits, os), "catalog"
 	}
 
-	if geminiAPIKey != "" {
-		if candidates, err := searchWithGemini(geminiAPIKey, query, os, hits); err == nil && len(candidates) > 0 {
-			return candidates, "gemini"
+	if llm != nil {
+		if llm.reserve() {
+			redacted := redactQuery(query)
+			llm.audit(redacted)
+			candidates, err := searchWithGemini(llm.client, redacted, os, hits)
+			if err == nil && len(candidates) > 0 {
+				return candidates, "gemini"
+			}
+			log.Printf("Gemini search failed, using catalog fallback: %v", err)
+		} else {
+			log.Printf("Gemini daily limit of %d reached, using catalog fallback", llm.dailyLimit)
 		}
-		log.Printf("Gemini search failed, using catalog fallback")
 	}
 
 	if len(hits) > 0 {
@@ -99,6 +129,27 @@ func searchCommands(query, os, geminiAPIKey string) ([]CommandCandidate, string)
 	return nil, ""
 }
 
+// withAlternatives returns a copy of candidates annotated with their catalog
+// alternatives and, when installed is given, the ones the client already has.
+func withAlternatives(candidates []CommandCandidate, installed []string) []CommandCandidate {
+	have := map[string]bool{}
+	for _, name := range installed {
+		have[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	out := make([]CommandCandidate, len(candidates))
+	for i, c := range candidates {
+		c.Alternatives = catalog.Alternatives(c.Name)
+		c.InstalledAlternatives = nil
+		for _, alt := range c.Alternatives {
+			if have[alt] {
+				c.InstalledAlternatives = append(c.InstalledAlternatives, alt)
+			}
+		}
+		out[i] = c
+	}
+	return out
+}
+
 func catalogHitsToCandidates(hits []catalog.SearchResult, os string) []CommandCandidate {
 	out := make([]CommandCandidate, 0, len(hits))
 	for _, hit := range hits {
@@ -136,7 +187,7 @@ func writeSearchResponse(w http.ResponseWriter, candidates []CommandCandidate, c
 }
 
 // searchWithGemini calls Gemini Flash when catalog confidence is low.
-func searchWithGemini(apiKey, query, os string, hints []catalog.SearchResult) ([]CommandCandidate, error) {
+func searchWithGemini(client *gemini.Client, query, os string, hints []catalog.SearchResult) ([]CommandCandidate, error) {
 	var hintLines strings.Builder
 	for i, h := range hints {
 		if i >= 3 {
@@ -154,60 +205,21 @@ OS: %s
 Catalog hints:
 %s`, query, os, hintLines.String())
 
-	body := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{"parts": []map[string]string{{"text": prompt}}},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.2,
-			"maxOutputTokens": 512,
-		},
-	}
-	jsonBody, _ := json.Marshal(body)
-
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=%s", apiKey)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 12 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
 
-	raw, err := io.ReadAll(io.LimitReader(resp.Body, 32<<10))
+	text, err := client.GenerateText(ctx, prompt, gemini.GenerationConfig{
+		Temperature:     0.2,
+		MaxOutputTokens: 512,
+	})
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("gemini status %d: %s", resp.StatusCode, string(raw))
-	}
-
-	return parseGeminiCandidates(raw)
+	return parseGeminiCandidates(text)
 }
 
-func parseGeminiCandidates(raw []byte) ([]CommandCandidate, error) {
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-	if err := json.Unmarshal(raw, &geminiResp); err != nil {
-		return nil, err
-	}
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty gemini response")
-	}
-
-	text := strings.TrimSpace(geminiResp.Candidates[0].Content.Parts[0].Text)
+func parseGeminiCandidates(text string) ([]CommandCandidate, error) {
+	text = strings.TrimSpace(text)
 	text = strings.TrimPrefix(text, "```json")
 	text = strings.TrimPrefix(text, "```")
 	text = strings.TrimSuffix(text, "```")
@@ -224,10 +236,11 @@ func parseGeminiCandidates(raw []byte) ([]CommandCandidate, error) {
 
 	out := make([]CommandCandidate, 0, len(parsed))
 	for _, p := range parsed {
-		name := strings.Fields(strings.TrimSpace(p.Name))[0]
-		if name == "" || name == "echo" {
+		fields := strings.Fields(p.Name)
+		if len(fields) == 0 || fields[0] == "echo" {
 			continue
 		}
+		name := fields[0]
 		usage := p.Usage
 		if usage == "" {
 			usage = name
@@ -246,6 +259,147 @@ func parseGeminiCandidates(raw []byte) ([]CommandCandidate, error) {
 	return out, nil
 }
 
+// APIv1CommandSnapshot handles GET /api/v1/commands/snapshot
+// Returns the whole embedded catalog so releases can ship it for offline installs.
+func (h *Handlers) APIv1CommandSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	etag := fmt.Sprintf(`"catalog-%s"`, catalog.Version())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Disposition", `attachment; filename="commands-snapshot.json"`)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(catalog.NewSnapshot()); err != nil {
+		log.Printf("Failed to encode catalog snapshot: %v", err)
+	}
+}
+
+// APIv1Commands handles GET /api/v1/commands?category=&os=&page=&per_page=
+// Pages through the whole enhanced catalog, including tools the caller has not
+// installed. The ETag changes only with the catalog version and the query.
+func (h *Handlers) APIv1Commands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	category := strings.ToLower(strings.TrimSpace(query.Get("category")))
+	os := query.Get("os")
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	if perPage <= 0 || perPage > 500 {
+		perPage = 100
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	querySum := sha256.Sum256([]byte(r.URL.RawQuery))
+	etag := fmt.Sprintf(`"catalog-%s-%x"`, catalog.Version(), querySum[:8])
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	matching := []catalog.CommandEntry{}
+	for _, entry := range catalog.All() {
+		if (category == "" || strings.ToLower(entry.Category) == category) && catalog.AvailableOn(entry, os) {
+			matching = append(matching, entry)
+		}
+	}
+	start := (page - 1) * perPage
+	if start > len(matching) {
+		start = len(matching)
+	}
+	end := start + perPage
+	if end > len(matching) {
+		end = len(matching)
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(matching)))
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":  catalog.Version(),
+		"commands": matching[start:end],
+		"total":    len(matching),
+		"page":     page,
+		"per_page": perPage,
+	}); err != nil {
+		log.Printf("Failed to encode command catalog: %v", err)
+	}
+}
+
+// APIv1CompareCommands handles GET /api/v1/commands/compare?names=curl,wget&os=
+// Returns catalog entries side by side so clients can help users choose
+// between similar tools. Installed status is left to the client.
+func (h *Handlers) APIv1CompareCommands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var names []string
+	for _, name := range strings.Split(r.URL.Query().Get("names"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) < 2 || len(names) > 5 {
+		http.Error(w, "Give between 2 and 5 command names", http.StatusBadRequest)
+		return
+	}
+
+	os := r.URL.Query().Get("os")
+	byName := map[string]catalog.CommandEntry{}
+	for _, entry := range catalog.All() {
+		byName[strings.ToLower(entry.Name)] = entry
+	}
+
+	commands := []map[string]interface{}{}
+	missing := []string{}
+	for _, name := range names {
+		entry, ok := byName[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		commands = append(commands, map[string]interface{}{
+			"name":         entry.Name,
+			"description":  entry.Description,
+			"category":     entry.Category,
+			"use_case":     catalog.UseCase(entry, os),
+			"available":    catalog.AvailableOn(entry, os),
+			"alternatives": catalog.Alternatives(entry.Name),
+			"homepage":     entry.Homepage,
+			"packages": map[string]string{
+				"apt":  entry.AptPackage,
+				"pkg":  entry.PkgPackage,
+				"dnf":  entry.DnfPackage,
+				"brew": entry.BrewPackage,
+				"arch": entry.ArchPackage,
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"commands": commands,
+		"missing":  missing,
+	}); err != nil {
+		log.Printf("Failed to encode command comparison: %v", err)
+	}
+}
+
 // --- Caching ---
 
 func ensureCacheTable(db *sql.DB) {
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/themobileprof/clipilot/server/catalog"
 	_ "modernc.org/sqlite"
 )
 
@@ -18,7 +20,7 @@ func TestSemanticSearchCatalog(t *testing.T) {
 	}
 	defer db.Close()
 
-	handler := HandleSemanticSearch(db, "")
+	handler := HandleSemanticSearch(db, "", 0)
 
 	body := `{"query":"check disk space on my phone","os":"linux","arch":"arm64"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/commands/search", bytes.NewReader([]byte(body)))
@@ -46,3 +48,125 @@ func TestSemanticSearchCatalog(t *testing.T) {
 		t.Fatal("legacy results alias missing")
 	}
 }
+
+func TestRedactQuery(t *testing.T) {
+	got := redactQuery("why can't I copy /home/bob/notes.txt to ~/backup on files.example.com as bob@laptop via 10.0.0.5:22")
+	for _, leak := range []string{"/home/bob", "~/backup", "example.com", "bob@laptop", "10.0.0.5"} {
+		if strings.Contains(got, leak) {
+			t.Fatalf("redacted query still contains %q: %s", leak, got)
+		}
+	}
+	if kept := redactQuery("extract archive.tar.gz"); kept != "extract archive.tar.gz" {
+		t.Fatalf("plain file name was redacted: %s", kept)
+	}
+}
+
+func TestOnlineLLMDailyLimit(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ensureLLMTables(db)
+
+	llm := &onlineLLM{db: db, dailyLimit: 2}
+	if !llm.reserve() || !llm.reserve() {
+		t.Fatal("requests within the limit were refused")
+	}
+	if llm.reserve() {
+		t.Fatal("request over the daily limit was allowed")
+	}
+}
+
+func TestAPIv1CommandsPaging(t *testing.T) {
+	h := &Handlers{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/commands?page=2&per_page=5", nil)
+	w := httptest.NewRecorder()
+	h.APIv1Commands(w, req)
+
+	var resp struct {
+		Commands []struct {
+			Name string `json:"name"`
+		} `json:"commands"`
+		Total int `json:"total"`
+		Page  int `json:"page"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Commands) != 5 || resp.Page != 2 || resp.Total < 10 {
+		t.Fatalf("got %d commands on page %d of %d", len(resp.Commands), resp.Page, resp.Total)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/commands?page=2&per_page=5", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	h.APIv1Commands(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("status %d, want 304 for matching ETag", w.Code)
+	}
+}
+
+func TestWithAlternativesInstalled(t *testing.T) {
+	got := withAlternatives([]CommandCandidate{{Name: "htop"}}, []string{"top", "ls"})
+	if strings.Join(got[0].Alternatives, ",") != "btop,top" {
+		t.Fatalf("alternatives = %v", got[0].Alternatives)
+	}
+	if strings.Join(got[0].InstalledAlternatives, ",") != "top" {
+		t.Fatalf("installed alternatives = %v", got[0].InstalledAlternatives)
+	}
+}
+
+func TestAPIv1CompareCommands(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/commands/compare?names=curl,wget,nosuchtool", nil)
+	w := httptest.NewRecorder()
+	(&Handlers{}).APIv1CompareCommands(w, req)
+
+	var resp struct {
+		Commands []map[string]interface{} `json:"commands"`
+		Missing  []string                 `json:"missing"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Commands) != 2 || resp.Commands[0]["name"] != "curl" || resp.Commands[1]["name"] != "wget" {
+		t.Fatalf("commands = %v", resp.Commands)
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "nosuchtool" {
+		t.Fatalf("missing = %v", resp.Missing)
+	}
+
+	w = httptest.NewRecorder()
+	(&Handlers{}).APIv1CompareCommands(w, httptest.NewRequest(http.MethodGet, "/api/v1/commands/compare?names=curl", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d for a single name, want 400", w.Code)
+	}
+}
+
+func TestAPIv1CommandSnapshot(t *testing.T) {
+	h := &Handlers{}
+	w := httptest.NewRecorder()
+	h.APIv1CommandSnapshot(w, httptest.NewRequest(http.MethodGet, "/api/v1/commands/snapshot", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	etag := w.Header().Get("ETag")
+	if etag != `"catalog-`+catalog.Version()+`"` {
+		t.Fatalf("ETag = %s, want the catalog version", etag)
+	}
+	var snap catalog.Snapshot
+	if err := json.NewDecoder(w.Body).Decode(&snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Count == 0 || snap.Count != len(snap.Commands) || snap.Version != catalog.Version() {
+		t.Fatalf("snapshot count/version = %d/%s with %d commands", snap.Count, snap.Version, len(snap.Commands))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/commands/snapshot", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	h.APIv1CommandSnapshot(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("conditional request = %d with %d bytes, want an empty 304", w.Code, w.Body.Len())
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// platformHeader is sent voluntarily by Clio on downloads, e.g.
+// "termux/aarch64 pkg" or "linux/amd64 apt"
+const platformHeader = "X-Clio-Platform"
+
+// PlatformCount is the number of downloads from one client platform
+type PlatformCount struct {
+	Platform  string `json:"platform"`
+	Downloads int    `json:"downloads"`
+}
+
+// clientPlatform returns a normalised platform label for a download request
+func clientPlatform(r *http.Request) string {
+	raw := strings.ToLower(strings.TrimSpace(r.Header.Get(platformHeader)))
+	if raw == "" {
+		if strings.HasPrefix(r.UserAgent(), "Mozilla/") {
+			return "web"
+		}
+		return "unknown"
+	}
+
+	var b strings.Builder
+	for _, c := range raw {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || strings.ContainsRune("/._- ", c) {
+			b.WriteRune(c)
+		}
+		if b.Len() >= 64 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "unknown"
+	}
+	return b.String()
+}
+
+// recordDownload logs one download of a module version with the client
+// platform. A failed insert is logged and does not fail the download.
+func (h *Handlers) recordDownload(r *http.Request, name, version string) {
+	if _, err := h.db.Exec(`
+		INSERT INTO module_downloads (module_name, module_version, platform) VALUES (?, ?, ?)
+	`, name, version, clientPlatform(r)); err != nil {
+		log.Printf("Failed to record download platform: %v", err)
+	}
+}
+
+// downloadsByPlatform returns per-platform download counts for a module, most popular first
+func (h *Handlers) downloadsByPlatform(name string) ([]PlatformCount, error) {
+	rows, err := h.db.Query(`
+		SELECT platform, COUNT(*)
+		FROM module_downloads
+		WHERE module_name = ?
+		GROUP BY platform
+		ORDER BY COUNT(*) DESC, platform
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []PlatformCount{}
+	for rows.Next() {
+		var c PlatformCount
+		if err := rows.Scan(&c.Platform, &c.Downloads); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
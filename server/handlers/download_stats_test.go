@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientPlatform(t *testing.T) {
+	cases := []struct {
+		header, userAgent, want string
+	}{
+		{"Termux/aarch64 pkg", "", "termux/aarch64 pkg"},
+		{"linux/amd64; DROP TABLE", "", "linux/amd64 drop table"},
+		{"", "Mozilla/5.0 (X11)", "web"},
+		{"", "curl/8.0", "unknown"},
+		{"!!!", "", "unknown"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/api/v1/modules/x/download", nil)
+		if c.header != "" {
+			r.Header.Set(platformHeader, c.header)
+		}
+		r.Header.Set("User-Agent", c.userAgent)
+		if got := clientPlatform(r); got != c.want {
+			t.Errorf("clientPlatform(%q, %q) = %q, want %q", c.header, c.userAgent, got, c.want)
+		}
+	}
+}
+
+func TestRecordDownloadIsImmediatelyCounted(t *testing.T) {
+	h := &Handlers{db: openTestDB(t)}
+	for _, platform := range []string{"termux/aarch64", "linux/amd64", "termux/aarch64"} {
+		r := httptest.NewRequest("GET", "/api/v1/modules/demo/download", nil)
+		r.Header.Set(platformHeader, platform)
+		h.recordDownload(r, "demo", "1.0.0")
+	}
+
+	counts, err := h.downloadsByPlatform("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0] != (PlatformCount{"termux/aarch64", 2}) || counts[1] != (PlatformCount{"linux/amd64", 1}) {
+		t.Fatalf("downloadsByPlatform = %+v, want termux/aarch64 x2 then linux/amd64 x1", counts)
+	}
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	"github.com/themobileprof/clipilot/server/scanner"
+)
+
+// Draft is a module being edited in the browser before it is published
+type Draft struct {
+	ID        int64
+	Name      string
+	Content   string
+	UpdatedAt time.Time
+}
+
+// draftTemplate pre-fills the editor for a new draft
+const draftTemplate = `name: my_module
+version: "1.0.0"
+description: What this module does
+tags:
+  - example
+
+flows:
+  main:
+    start: hello
+    steps:
+      hello:
+        type: action
+        message: "Saying hello..."
+        command: "echo hello"
+        next: done
+      done:
+        type: terminal
+        message: "Done!"
+`
+
+// DraftsPage lists the current user's drafts (authenticated users only)
+func (h *Handlers) DraftsPage(w http.ResponseWriter, r *http.Request) {
+	username := h.auth.GetUsername(r)
+
+	rows, err := h.db.Query(`
+		SELECT id, name, updated_at
+		FROM module_drafts
+		WHERE owner = ?
+		ORDER BY updated_at DESC
+	`, username)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var drafts []Draft
+	for rows.Next() {
+		var d Draft
+		if err := rows.Scan(&d.ID, &d.Name, &d.UpdatedAt); err != nil {
+			log.Printf("Scan error: %v", err)
+			continue
+		}
+		drafts = append(drafts, d)
+	}
+
+	data := map[string]interface{}{
+		"Title":    "My Drafts",
+		"Drafts":   drafts,
+		"LoggedIn": true,
+		"Session":  h.auth.GetSession(r),
+		"Username": username,
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "drafts.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// DraftEditorPage shows the YAML editor for a draft (?id=N) or a new one
+func (h *Handlers) DraftEditorPage(w http.ResponseWriter, r *http.Request) {
+	draft := Draft{Content: draftTemplate}
+
+	if idParam := r.URL.Query().Get("id"); idParam != "" {
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+			return
+		}
+		d, err := h.getDraft(id, h.auth.GetUsername(r))
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Database error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		draft = d
+	}
+
+	data := map[string]interface{}{
+		"Title":    "Edit Draft",
+		"Draft":    draft,
+		"LoggedIn": true,
+		"Session":  h.auth.GetSession(r),
+		"Username": h.auth.GetUsername(r),
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "draft-editor.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// APIValidateDraft handles POST /api/drafts/validate with raw YAML as the body.
+// It runs the same checks as an upload without storing anything.
+func (h *Handlers) APIValidateDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024+1))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	result := map[string]interface{}{"valid": true}
+	var module models.Module
+	if len(content) > 1024*1024 {
+		result = map[string]interface{}{"valid": false, "error": "YAML too large (max 1MB)"}
+	} else if err := yaml.Unmarshal(content, &module); err != nil {
+		result = map[string]interface{}{"valid": false, "error": "Invalid YAML syntax: " + err.Error()}
+	} else if err := validateModule(&module); err != nil {
+		result = map[string]interface{}{"valid": false, "error": "Validation failed: " + err.Error()}
+	} else {
+		report := scanner.ScanModule(&module)
+		result["risk_level"] = report.Level
+		result["findings"] = report.Findings
+		if report.Level == scanner.RiskHigh && !h.auth.IsAdmin(r) {
+			result["valid"] = false
+			result["error"] = "Security scan would block this module"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Failed to encode validation response: %v", err)
+	}
+}
+
+// APISaveDraft handles POST /api/drafts/save (form: id, content).
+// An empty id creates a new draft.
+func (h *Handlers) APISaveDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := h.auth.GetUsername(r)
+	content := r.FormValue("content")
+	if strings.TrimSpace(content) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Draft is empty"})
+		return
+	}
+	if len(content) > 1024*1024 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "YAML too large (max 1MB)"})
+		return
+	}
+
+	// Best-effort name for the drafts list; drafts may be invalid YAML
+	var module models.Module
+	_ = yaml.Unmarshal([]byte(content), &module)
+	name := module.Name
+
+	var id int64
+	if idParam := r.FormValue("id"); idParam != "" {
+		var err error
+		id, err = strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Invalid draft ID"})
+			return
+		}
+		res, err := h.db.Exec(`
+			UPDATE module_drafts SET name = ?, content = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND owner = ?
+		`, name, content, id, username)
+		if err != nil {
+			log.Printf("Failed to update draft: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to save draft"})
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "Draft not found"})
+			return
+		}
+	} else {
+		res, err := h.db.Exec(`
+			INSERT INTO module_drafts (owner, name, content) VALUES (?, ?, ?)
+		`, username, name, content)
+		if err != nil {
+			log.Printf("Failed to create draft: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to save draft"})
+			return
+		}
+		id, _ = res.LastInsertId()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "id": id})
+}
+
+// APIPublishDraft handles POST /api/drafts/publish (form: id, overwrite).
+// The draft goes through the same validation as an upload and is deleted once published.
+func (h *Handlers) APIPublishDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Invalid draft ID"})
+		return
+	}
+	username := h.auth.GetUsername(r)
+	draft, err := h.getDraft(id, username)
+	if err == sql.ErrNoRows {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "Draft not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+
+	status, message, url, err := h.publishModule(r, []byte(draft.Content), "draft-"+strconv.FormatInt(id, 10)+".yaml",
+		r.FormValue("overwrite") == "true")
+	if err != nil {
+		writeJSON(w, status, map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM module_drafts WHERE id = ? AND owner = ?", id, username); err != nil {
+		log.Printf("Warning: failed to delete published draft %d: %v", id, err)
+	}
+	writeJSON(w, status, map[string]interface{}{"success": true, "message": message, "url": url})
+}
+
+// APIDeleteDraft handles POST /api/drafts/delete (form: id)
+func (h *Handlers) APIDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.Exec("DELETE FROM module_drafts WHERE id = ? AND owner = ?", id, h.auth.GetUsername(r)); err != nil {
+		log.Printf("Failed to delete draft: %v", err)
+		http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/drafts", http.StatusSeeOther)
+}
+
+// getDraft loads a draft owned by username
+func (h *Handlers) getDraft(id int64, username string) (Draft, error) {
+	var d Draft
+	err := h.db.QueryRow(`
+		SELECT id, name, content, updated_at
+		FROM module_drafts
+		WHERE id = ? AND owner = ?
+	`, id, username).Scan(&d.ID, &d.Name, &d.Content, &d.UpdatedAt)
+	return d, err
+}
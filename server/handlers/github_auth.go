@@ -3,10 +3,12 @@ package handlers
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/themobileprof/clipilot/server/auth"
@@ -86,15 +88,72 @@ func (h *Handlers) GitHubCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Persist the contributor so their uploads, keys and role survive restarts
+	username, role, err := h.upsertGitHubUser(ghUser)
+	if err != nil {
+		log.Printf("Failed to save GitHub user %s: %v", ghUser.Login, err)
+		http.Error(w, "Failed to save user", http.StatusInternalServerError)
+		return
+	}
+
 	// Create session for GitHub user
-	h.auth.SetGitHubSession(w, ghUser)
+	h.auth.SetGitHubSession(w, ghUser, username, role == "admin")
 
-	log.Printf("GitHub user logged in: %s (%s)", ghUser.Login, ghUser.Name)
+	log.Printf("GitHub user logged in: %s as %s (%s)", ghUser.Login, username, role)
 
 	// Redirect to home page
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// upsertGitHubUser finds the user linked to a GitHub account, creating a
+// contributor on first login, and returns their registry username and role.
+// A login that clashes with an existing password account gets the GitHub ID
+// appended rather than taking the account over.
+func (h *Handlers) upsertGitHubUser(ghUser *auth.GitHubUser) (string, string, error) {
+	githubID := strconv.FormatInt(ghUser.ID, 10)
+
+	var username, role string
+	err := h.db.QueryRow("SELECT username, role FROM users WHERE github_id = ?", githubID).Scan(&username, &role)
+	if err == nil {
+		_, err = h.db.Exec("UPDATE users SET avatar_url = ?, updated_at = CURRENT_TIMESTAMP WHERE github_id = ?",
+			ghUser.AvatarURL, githubID)
+		return username, role, err
+	}
+	if err != sql.ErrNoRows {
+		return "", "", err
+	}
+
+	username = ghUser.Login
+	var taken int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&taken); err != nil {
+		return "", "", err
+	}
+	if taken > 0 {
+		username = ghUser.Login + "-" + githubID
+	}
+
+	noreply := fmt.Sprintf("%s+%s@users.noreply.github.com", githubID, ghUser.Login)
+	email := ghUser.Email
+	if email == "" {
+		email = noreply
+	} else if err := h.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&taken); err != nil {
+		return "", "", err
+	} else if taken > 0 {
+		email = noreply
+	}
+
+	role = "contributor"
+	_, err = h.db.Exec(`
+		INSERT INTO users (username, email, github_id, avatar_url, role, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`, username, email, githubID, ghUser.AvatarURL, role)
+	if err != nil {
+		return "", "", err
+	}
+	log.Printf("Created contributor %s for GitHub user %s", username, ghUser.Login)
+	return username, role, nil
+}
+
 // generateState creates a random state string for OAuth CSRF protection
 func generateState() string {
 	b := make([]byte, 32)
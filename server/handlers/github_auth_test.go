@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/themobileprof/clipilot/server/auth"
+)
+
+func TestUpsertGitHubUser(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO users (username, email, password_hash, role) VALUES ('alice', 'alice@example.com', 'x', 'admin')`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	// Same login as an existing password account must not take it over
+	gh := &auth.GitHubUser{Login: "alice", ID: 42, Email: "alice@example.com"}
+	username, role, err := h.upsertGitHubUser(gh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "alice-42" || role != "contributor" {
+		t.Fatalf("got %s/%s, want alice-42/contributor", username, role)
+	}
+
+	// Second login finds the linked user instead of creating another
+	gh.AvatarURL = "https://avatars.example.com/42"
+	again, _, err := h.upsertGitHubUser(gh)
+	if err != nil || again != username {
+		t.Fatalf("second login got %q, %v", again, err)
+	}
+	var count int
+	_ = db.QueryRow("SELECT COUNT(*) FROM users WHERE github_id = '42'").Scan(&count)
+	if count != 1 {
+		t.Fatalf("%d users linked to GitHub ID 42, want 1", count)
+	}
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"crypto/sha256"
 	"database/sql"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
@@ -23,6 +25,8 @@ import (
 	"github.com/themobileprof/clipilot/server/auth"
 	"github.com/themobileprof/clipilot/server/bootstrap"
 	"github.com/themobileprof/clipilot/server/migrations"
+	"github.com/themobileprof/clipilot/server/notify"
+	"github.com/themobileprof/clipilot/server/scanner"
 )
 
 type Config struct {
@@ -35,6 +39,19 @@ type Config struct {
 	GitHubClientID     string
 	GitHubClientSecret string
 	BaseURL            string
+	// ReportHideThreshold hides a module once this many users have open
+	// abuse reports against it (0 disables auto-hiding)
+	ReportHideThreshold int
+	// ReviewUploads holds uploads from non-admins as pending until an admin
+	// approves them at /admin/review
+	ReviewUploads bool
+	// Notifier delivers moderation and request notifications (nil disables them)
+	Notifier notify.Notifier
+	// MinClientVersion is the oldest Clio release still supported; older
+	// clients are told to upgrade before running destructive operations
+	MinClientVersion string
+	// LatestClientVersion overrides the version of the active install script
+	LatestClientVersion string
 }
 
 type Handlers struct {
@@ -43,6 +60,9 @@ type Handlers struct {
 	templates   *template.Template
 	auth        *auth.Manager
 	githubOAuth *oauth2.Config
+	suggestStmt *sql.Stmt
+	notifier    notify.Notifier
+	reporterKey []byte
 }
 
 type ModuleRecord struct {
@@ -55,6 +75,13 @@ type ModuleRecord struct {
 	UploadedBy  string
 	FilePath    string
 	Downloads   int
+	RunCount    int
+	SuccessRate float64
+	RiskLevel   string
+	Checksum    string // SHA-256 of the YAML file, hex
+	Platforms   []PlatformCount
+	Status      string // Review state: pending, approved, rejected
+	ReviewNotes string
 }
 
 // First-class Clio setup wizards (install/configure — run once).
@@ -86,6 +113,33 @@ func New(cfg Config) *Handlers {
 	if _, err := db.Exec(initialSchema); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
+	if err := migrations.EnsureColumns(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := migrations.EnsureChangeTriggers(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := seedCategories(db); err != nil {
+		log.Printf("Warning: failed to seed categories: %v", err)
+	}
+	if err := backfillChecksums(db); err != nil {
+		log.Printf("Warning: failed to backfill module checksums: %v", err)
+	}
+	if err := backfillRiskReports(db); err != nil {
+		log.Printf("Warning: failed to backfill module risk reports: %v", err)
+	}
+
+	suggestStmt, err := db.Prepare(suggestModulesQuery)
+	if err != nil {
+		log.Fatalf("Failed to prepare suggest query: %v", err)
+	}
+
+	// Run reports are keyed on an HMAC of the client IP; the key lives next
+	// to the database so quotas survive restarts
+	reporterKey, err := loadReporterKey(filepath.Join(filepath.Dir(cfg.DBPath), "reporter.key"))
+	if err != nil {
+		log.Fatalf("Failed to load run report key: %v", err)
+	}
 
 	// Bootstrap: Ensure admin user exists in database
 	if err := EnsureAdminUser(db, cfg.AdminUser, cfg.AdminPass); err != nil {
@@ -135,12 +189,20 @@ func New(cfg Config) *Handlers {
 		log.Println("GitHub OAuth not configured (GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET required)")
 	}
 
+	notifier := cfg.Notifier
+	if notifier == nil {
+		notifier = notify.Nop{}
+	}
+
 	return &Handlers{
 		config:      cfg,
 		db:          db,
 		templates:   templates,
 		auth:        authMgr,
 		githubOAuth: githubOAuth,
+		suggestStmt: suggestStmt,
+		notifier:    notifier,
+		reporterKey: reporterKey,
 	}
 }
 
@@ -167,7 +229,7 @@ func (h *Handlers) Home(w http.ResponseWriter, r *http.Request) {
 
 	session := h.auth.GetSession(r)
 	var moduleCount int
-	_ = h.db.QueryRow("SELECT COUNT(*) FROM modules").Scan(&moduleCount)
+	_ = h.db.QueryRow("SELECT COUNT(*) FROM modules WHERE hidden = 0 AND status = 'approved'").Scan(&moduleCount)
 
 	data := map[string]interface{}{
 		"Title":       "CLIPilot Registry",
@@ -187,9 +249,13 @@ func (h *Handlers) Home(w http.ResponseWriter, r *http.Request) {
 // ListModules displays all modules
 func (h *Handlers) ListModules(w http.ResponseWriter, r *http.Request) {
 	query := `
-		SELECT id, name, version, description, author, uploaded_at, uploaded_by, downloads
-		FROM modules
-		ORDER BY uploaded_at DESC
+		SELECT m.id, m.name, m.version, m.description, m.author, m.uploaded_at, m.uploaded_by, m.downloads,
+		       COUNT(s.id), COALESCE(AVG(s.success) * 100, 0), COALESCE(m.risk_level, 'low')
+		FROM modules m
+		LEFT JOIN module_run_stats s ON s.module_name = m.name
+		WHERE m.hidden = 0 AND m.status = 'approved'
+		GROUP BY m.id
+		ORDER BY m.uploaded_at DESC
 	`
 
 	rows, err := h.db.Query(query)
@@ -204,7 +270,8 @@ func (h *Handlers) ListModules(w http.ResponseWriter, r *http.Request) {
 	var automationModules []ModuleRecord
 	for rows.Next() {
 		var m ModuleRecord
-		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.Author, &m.UploadedAt, &m.UploadedBy, &m.Downloads); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.Author, &m.UploadedAt, &m.UploadedBy, &m.Downloads,
+			&m.RunCount, &m.SuccessRate, &m.RiskLevel); err != nil {
 			log.Printf("Scan error: %v", err)
 			continue
 		}
@@ -242,11 +309,12 @@ func (h *Handlers) GetModule(w http.ResponseWriter, r *http.Request) {
 
 	moduleID := parts[1]
 	var m ModuleRecord
+	var status string
 	err := h.db.QueryRow(`
-		SELECT id, name, version, file_path, downloads
+		SELECT id, name, version, file_path, downloads, uploaded_by, COALESCE(status, 'approved'), COALESCE(risk_level, 'low')
 		FROM modules
-		WHERE id = ?
-	`, moduleID).Scan(&m.ID, &m.Name, &m.Version, &m.FilePath, &m.Downloads)
+		WHERE id = ? AND hidden = 0
+	`, moduleID).Scan(&m.ID, &m.Name, &m.Version, &m.FilePath, &m.Downloads, &m.UploadedBy, &status, &m.RiskLevel)
 
 	if err == sql.ErrNoRows {
 		http.NotFound(w, r)
@@ -258,12 +326,27 @@ func (h *Handlers) GetModule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Modules awaiting review (or rejected) are only served to their author and admins
+	if status != "approved" {
+		if !h.auth.IsAdmin(r) && h.auth.GetUsername(r) != m.UploadedBy {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.yaml", m.Name, m.Version))
+		w.Header().Set("X-Risk-Level", m.RiskLevel)
+		http.ServeFile(w, r, m.FilePath)
+		return
+	}
+
 	// Increment download counter
 	_, _ = h.db.Exec("UPDATE modules SET downloads = downloads + 1 WHERE id = ?", m.ID)
+	h.recordDownload(r, m.Name, m.Version)
 
 	// Serve file
 	w.Header().Set("Content-Type", "application/x-yaml")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.yaml", m.Name, m.Version))
+	w.Header().Set("X-Risk-Level", m.RiskLevel)
 	http.ServeFile(w, r, m.FilePath)
 }
 
@@ -336,6 +419,19 @@ func validateModule(module *models.Module) error {
 	if !hasValidFlow {
 		return fmt.Errorf("at least one flow with steps is required")
 	}
+	if flow, ok := module.Flows[models.UninstallFlow]; ok && (flow == nil || len(flow.Steps) == 0) {
+		return fmt.Errorf("flow '%s': must have steps (remove it if the module has nothing to clean up)", models.UninstallFlow)
+	}
+
+	if module.TimeoutSeconds < 0 || module.TimeoutSeconds > maxTimeoutSeconds {
+		return fmt.Errorf("timeout_seconds must be between 1 and %d, or omitted", maxTimeoutSeconds)
+	}
+	if err := validateEnv(module.Env); err != nil {
+		return err
+	}
+	if err := validateEnvironment(module.Environment); err != nil {
+		return err
+	}
 
 	// Validate each step in each flow
 	validTypes := map[string]bool{
@@ -343,6 +439,9 @@ func validateModule(module *models.Module) error {
 		"action":      true,
 		"branch":      true,
 		"terminal":    true,
+		"process":     true,
+		"prompt":      true,
+		"goto_flow":   true,
 	}
 
 	for flowName, flow := range module.Flows {
@@ -351,7 +450,7 @@ func validateModule(module *models.Module) error {
 				return fmt.Errorf("flow '%s', step '%s': type is required", flowName, stepKey)
 			}
 			if !validTypes[step.Type] {
-				return fmt.Errorf("flow '%s', step '%s': invalid type '%s' (must be: instruction, action, branch, or terminal)", flowName, stepKey, step.Type)
+				return fmt.Errorf("flow '%s', step '%s': invalid type '%s' (must be: instruction, action, branch, terminal, process, prompt, or goto_flow)", flowName, stepKey, step.Type)
 			}
 			if step.Type == "action" && step.Command == "" {
 				return fmt.Errorf("flow '%s', step '%s': command is required for action steps", flowName, stepKey)
@@ -359,8 +458,39 @@ func validateModule(module *models.Module) error {
 			if step.Type == "branch" && step.BasedOn == "" {
 				return fmt.Errorf("flow '%s', step '%s': based_on is required for branch steps", flowName, stepKey)
 			}
+			if step.Type == "goto_flow" {
+				if step.Flow == "" {
+					return fmt.Errorf("flow '%s', step '%s': flow is required for goto_flow steps", flowName, stepKey)
+				}
+				if step.Flow == flowName {
+					return fmt.Errorf("flow '%s', step '%s': goto_flow cannot jump to its own flow", flowName, stepKey)
+				}
+				if target, ok := module.Flows[step.Flow]; !ok || target == nil {
+					return fmt.Errorf("flow '%s', step '%s': flow '%s' does not exist in this module", flowName, stepKey, step.Flow)
+				} else if len(target.Steps) == 0 {
+					return fmt.Errorf("flow '%s', step '%s': flow '%s' has no steps", flowName, stepKey, step.Flow)
+				}
+			} else if step.Flow != "" {
+				return fmt.Errorf("flow '%s', step '%s': flow is only allowed on goto_flow steps", flowName, stepKey)
+			}
+		}
+		if err := validateStepOptions(flowName, flow); err != nil {
+			return err
 		}
 	}
+	validations := 0
+	for _, flow := range module.Flows {
+		for _, step := range flow.Steps {
+			validations += len(step.Validate)
+		}
+	}
+	if validations > maxValidationResults {
+		// Run reports carry one result per check and reject more than this
+		return fmt.Errorf("too many validate checks (max %d per module)", maxValidationResults)
+	}
+	if cycle := gotoFlowCycle(module); cycle != nil {
+		return fmt.Errorf("goto_flow steps form a cycle: %s", strings.Join(cycle, " -> "))
+	}
 
 	// Validate file size constraints
 	if len(module.Description) > 500 {
@@ -424,71 +554,83 @@ func (h *Handlers) APIUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	status, message, url, err := h.publishModule(r, data, header.Filename, overwrite)
+	if err != nil {
+		writeJSON(w, status, map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	writeJSON(w, status, map[string]interface{}{"success": true, "message": message, "url": url})
+}
+
+// publishModule validates, scans and stores module YAML uploaded by the
+// current user. It returns the HTTP status to report along with either a
+// success message and the module's page URL, or the error to show the uploader.
+func (h *Handlers) publishModule(r *http.Request, data []byte, filename string, overwrite bool) (int, string, string, error) {
 	// Parse YAML
 	var module models.Module
 	if err := yaml.Unmarshal(data, &module); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, `{"success": false, "error": "Invalid YAML syntax: %s"}`,
-			strings.ReplaceAll(err.Error(), `"`, `\"`))
-		return
+		return http.StatusBadRequest, "", "", fmt.Errorf("Invalid YAML syntax: %s", err)
 	}
 
 	// Comprehensive validation
 	if err := validateModule(&module); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, `{"success": false, "error": "Validation failed: %s"}`,
-			strings.ReplaceAll(err.Error(), `"`, `\"`))
-		return
+		return http.StatusBadRequest, "", "", fmt.Errorf("Validation failed: %s", err)
+	}
+
+	// Static scan of step commands: high-risk modules are only accepted from admins
+	report := scanner.ScanModule(&module)
+	if report.Level == scanner.RiskHigh && !h.auth.IsAdmin(r) {
+		var reasons []string
+		for _, f := range report.Findings {
+			if f.Severity == scanner.RiskHigh {
+				reasons = append(reasons, fmt.Sprintf("flow '%s', step '%s': %s", f.Flow, f.Step, f.Message))
+			}
+		}
+		return http.StatusBadRequest, "", "", fmt.Errorf("Security scan blocked upload: %s", strings.Join(reasons, "; "))
 	}
 
 	// Check for duplicates
 	var existingID int
-	var existingFilePath string
-	err = h.db.QueryRow("SELECT id, file_path FROM modules WHERE name = ? AND version = ?",
-		module.Name, module.Version).Scan(&existingID, &existingFilePath)
+	var existingFilePath, existingOwner string
+	err := h.db.QueryRow("SELECT id, file_path, uploaded_by FROM modules WHERE name = ? AND version = ?",
+		module.Name, module.Version).Scan(&existingID, &existingFilePath, &existingOwner)
 
 	moduleExists := (err == nil)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("Database error checking for duplicates: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "", "", fmt.Errorf("Internal server error")
 	}
 
 	if moduleExists && !overwrite {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusConflict)
-		fmt.Fprintf(w, `{"success": false, "error": "Module '%s' version %s already exists. Use overwrite=true to update."}`,
+		return http.StatusConflict, "", "", fmt.Errorf("Module '%s' version %s already exists. Use overwrite=true to update.",
 			module.Name, module.Version)
-		return
 	}
 
-	// Save file
-	filename := fmt.Sprintf("%s-%s-%d.yaml", module.Name, module.Version, time.Now().Unix())
-	savePath := filepath.Join(h.config.UploadsDir, filename)
-
-	outFile, err := os.Create(savePath)
-	if err != nil {
-		log.Printf("Failed to create file: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, `{"success": false, "error": "Failed to save file"}`)
-		return
+	// Only the original uploader or an admin may replace an existing version
+	if moduleExists && existingOwner != h.auth.GetUsername(r) && !h.auth.IsAdmin(r) {
+		return http.StatusForbidden, "", "", fmt.Errorf("Module '%s' version %s belongs to another user",
+			module.Name, module.Version)
 	}
-	defer outFile.Close()
 
-	if _, err := outFile.Write(data); err != nil {
+	// Save file
+	saveName := fmt.Sprintf("%s-%s-%d.yaml", module.Name, module.Version, time.Now().Unix())
+	savePath := filepath.Join(h.config.UploadsDir, saveName)
+
+	if err := os.WriteFile(savePath, data, 0644); err != nil {
 		log.Printf("Failed to write file: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(w, `{"success": false, "error": "Failed to write file"}`)
-		return
+		return http.StatusInternalServerError, "", "", fmt.Errorf("Failed to save file")
 	}
 
 	// Insert or update database
 	username := h.auth.GetUsername(r)
 
+	// Category tags are stored under their canonical taxonomy name
+	if index, err := loadCategoryIndex(h.db); err != nil {
+		log.Printf("Warning: failed to load categories: %v", err)
+	} else {
+		module.Tags = canonicalizeTags(index, module.Tags)
+	}
+
 	// Marshal tags to JSON
 	tagsJSON := "[]"
 	if len(module.Tags) > 0 {
@@ -499,21 +641,29 @@ func (h *Handlers) APIUpload(w http.ResponseWriter, r *http.Request) {
 		tagsJSON = "[" + strings.Join(tagsList, ",") + "]"
 	}
 
+	providesJSON, _ := json.Marshal(append([]string{}, module.Provides...))
+	checksum := checksumOf(data)
+	riskReport, _ := json.Marshal(report)
+
+	// New content from non-admins waits in the review queue, including overwrites
+	status := "approved"
+	if h.config.ReviewUploads && !h.auth.IsAdmin(r) {
+		status = "pending"
+	}
+
 	if moduleExists {
 		// Update existing module
 		_, err = h.db.Exec(`
 		UPDATE modules
-		SET description = ?, author = ?, tags = ?, uploaded_by = ?, github_user = ?, file_path = ?, original_filename = ?, uploaded_at = CURRENT_TIMESTAMP
+		SET description = ?, author = ?, tags = ?, uploaded_by = ?, github_user = ?, file_path = ?, original_filename = ?, risk_level = ?, risk_report = ?, provides = ?, checksum = ?, status = ?,
+		    review_notes = NULL, reviewed_by = NULL, reviewed_at = NULL, uploaded_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-		`, module.Description, module.Metadata.Author, tagsJSON, username, h.getGitHubUsername(r), savePath, header.Filename, existingID)
+		`, module.Description, module.Metadata.Author, tagsJSON, username, h.getGitHubUsername(r), savePath, filename, report.Level, string(riskReport), string(providesJSON), checksum, status, existingID)
 
 		if err != nil {
 			log.Printf("Database update error: %v", err)
 			os.Remove(savePath) // Clean up new file on DB error
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintf(w, `{"success": false, "error": "Failed to update module metadata"}`)
-			return
+			return http.StatusInternalServerError, "", "", fmt.Errorf("Failed to update module metadata")
 		}
 
 		// Delete old file after successful DB update
@@ -523,34 +673,40 @@ func (h *Handlers) APIUpload(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		log.Printf("Module updated successfully: %s v%s by %s", module.Name, module.Version, username)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"success": true, "message": "Module '%s' v%s updated successfully"}`,
-			module.Name, module.Version)
-	} else {
-		// Insert new module
-		_, err = h.db.Exec(`
-			INSERT INTO modules (name, version, description, author, tags, uploaded_by, github_user, file_path, original_filename)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`, module.Name, module.Version, module.Description,
-			module.Metadata.Author, tagsJSON, username, h.getGitHubUsername(r), savePath, header.Filename)
+		h.recordModuleDiff(&module)
 
-		if err != nil {
-			log.Printf("Database insert error: %v", err)
-			os.Remove(savePath) // Clean up file on DB error
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintf(w, `{"success": false, "error": "Failed to save module metadata"}`)
-			return
+		log.Printf("Module updated successfully: %s v%s by %s (%s)", module.Name, module.Version, username, status)
+		message := fmt.Sprintf("Module '%s' v%s updated successfully", module.Name, module.Version)
+		if status == "pending" {
+			h.notifyModuleSubmitted(module.Name, module.Version, username)
+			message = fmt.Sprintf("Module '%s' v%s updated and submitted for review", module.Name, module.Version)
 		}
+		return http.StatusOK, message, fmt.Sprintf("%s/modules/%d", h.config.BaseURL, existingID), nil
+	}
 
-		log.Printf("Module uploaded successfully: %s v%s by %s", module.Name, module.Version, username)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		fmt.Fprintf(w, `{"success": true, "message": "Module '%s' v%s uploaded successfully"}`,
-			module.Name, module.Version)
+	// Insert new module
+	result, err := h.db.Exec(`
+		INSERT INTO modules (name, version, description, author, tags, uploaded_by, github_user, file_path, original_filename, risk_level, risk_report, provides, checksum, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, module.Name, module.Version, module.Description,
+		module.Metadata.Author, tagsJSON, username, h.getGitHubUsername(r), savePath, filename, report.Level, string(riskReport), string(providesJSON), checksum, status)
+
+	if err != nil {
+		log.Printf("Database insert error: %v", err)
+		os.Remove(savePath) // Clean up file on DB error
+		return http.StatusInternalServerError, "", "", fmt.Errorf("Failed to save module metadata")
+	}
+
+	h.recordModuleDiff(&module)
+
+	moduleID, _ := result.LastInsertId()
+	log.Printf("Module uploaded successfully: %s v%s by %s (%s)", module.Name, module.Version, username, status)
+	message := fmt.Sprintf("Module '%s' v%s uploaded successfully", module.Name, module.Version)
+	if status == "pending" {
+		h.notifyModuleSubmitted(module.Name, module.Version, username)
+		message = fmt.Sprintf("Module '%s' v%s submitted for review; it will be listed once an admin approves it", module.Name, module.Version)
 	}
+	return http.StatusCreated, message, fmt.Sprintf("%s/modules/%d", h.config.BaseURL, moduleID), nil
 }
 
 // MyModules shows modules uploaded by the current user
@@ -558,7 +714,7 @@ func (h *Handlers) MyModules(w http.ResponseWriter, r *http.Request) {
 	username := h.auth.GetUsername(r)
 
 	rows, err := h.db.Query(`
-		SELECT id, name, version, description, uploaded_at, downloads
+		SELECT id, name, version, description, uploaded_at, downloads, COALESCE(status, 'approved'), COALESCE(review_notes, '')
 		FROM modules
 		WHERE uploaded_by = ?
 		ORDER BY uploaded_at DESC
@@ -574,13 +730,26 @@ func (h *Handlers) MyModules(w http.ResponseWriter, r *http.Request) {
 	var modules []ModuleRecord
 	for rows.Next() {
 		var m ModuleRecord
-		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.UploadedAt, &m.Downloads); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.UploadedAt, &m.Downloads, &m.Status, &m.ReviewNotes); err != nil {
 			log.Printf("Scan error: %v", err)
 			continue
 		}
 		modules = append(modules, m)
 	}
 
+	// Platform breakdown helps authors decide which package-manager branches matter
+	for i := range modules {
+		platforms, err := h.downloadsByPlatform(modules[i].Name)
+		if err != nil {
+			log.Printf("Failed to load download platforms: %v", err)
+			continue
+		}
+		if len(platforms) > 3 {
+			platforms = platforms[:3]
+		}
+		modules[i].Platforms = platforms
+	}
+
 	data := map[string]interface{}{
 		"Title":    "My Modules",
 		"Modules":  modules,
@@ -670,8 +839,9 @@ func (h *Handlers) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 // API endpoints for CLI access
 func (h *Handlers) APIListModules(w http.ResponseWriter, r *http.Request) {
 	rows, err := h.db.Query(`
-		SELECT id, name, version, description, author, COALESCE(tags, '[]'), downloads
+		SELECT id, name, version, COALESCE(description, ''), COALESCE(author, ''), COALESCE(tags, '[]'), downloads, COALESCE(checksum, ''), COALESCE(risk_level, 'low')
 		FROM modules
+		WHERE hidden = 0 AND status = 'approved'
 		ORDER BY uploaded_at DESC
 	`)
 	if err != nil {
@@ -681,37 +851,37 @@ func (h *Handlers) APIListModules(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write([]byte("["))
-
-	first := true
+	modules := []map[string]interface{}{}
 	for rows.Next() {
 		var m ModuleRecord
 		var tagsJSON string
-		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.Author, &tagsJSON, &m.Downloads); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.Author, &tagsJSON, &m.Downloads, &m.Checksum, &m.RiskLevel); err != nil {
 			continue
 		}
-
-		if !first {
-			_, _ = w.Write([]byte(","))
-		}
-		first = false
-
-		fmt.Fprintf(w, `{"id":%d,"name":"%s","version":"%s","description":"%s","author":"%s","tags":%s,"downloads":%d}`,
-			m.ID, m.Name, m.Version, m.Description, m.Author, tagsJSON, m.Downloads)
+		tags := []string{}
+		_ = json.Unmarshal([]byte(tagsJSON), &tags)
+		modules = append(modules, map[string]interface{}{
+			"id":              m.ID,
+			"name":            m.Name,
+			"version":         m.Version,
+			"description":     m.Description,
+			"author":          m.Author,
+			"tags":            tags,
+			"downloads":       m.Downloads,
+			"checksum_sha256": m.Checksum,
+			"risk_level":      m.RiskLevel,
+		})
 	}
 
-	_, _ = w.Write([]byte("]"))
-}
-
-func (h *Handlers) APIGetModule(w http.ResponseWriter, r *http.Request) {
-	// Same as GetModule but with JSON response option
-	h.GetModule(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(modules); err != nil {
+		log.Printf("Failed to encode module list: %v", err)
+	}
 }
 
 // HandleSemanticSearch wraps the semantic search handler
-func (h *Handlers) HandleSemanticSearch(geminiAPIKey string) http.HandlerFunc {
-	return HandleSemanticSearch(h.db, geminiAPIKey)
+func (h *Handlers) HandleSemanticSearch(geminiAPIKey string, geminiDailyLimit int) http.HandlerFunc {
+	return HandleSemanticSearch(h.db, geminiAPIKey, geminiDailyLimit)
 }
 
 // authenticateUser checks username/password against users table
@@ -764,3 +934,35 @@ func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"status": "ok", "database": "%s", "timestamp": "%s"}`,
 		dbStatus, time.Now().Format(time.RFC3339))
 }
+
+// writeJSON writes body as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// writeCachedJSON sends body with an ETag over the encoded response, so any
+// change to the payload (not just the module file) invalidates cached copies,
+// and answers 304 when the client already holds it
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := fmt.Sprintf(`"%x"`, sum[:16])
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/themobileprof/clipilot/internal/llm/gemini"
+)
+
+// onlineLLM wraps the Gemini client with a per-day request quota and an
+// audit log of every query sent to the external API
+type onlineLLM struct {
+	client     *gemini.Client
+	db         *sql.DB
+	dailyLimit int // 0 means unlimited
+}
+
+// redaction rules applied to a query before it leaves the server, in order
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)*`), "<user>@<host>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`(^|[\s"'=:(])~?/[^\s"')]+`), "${1}<path>"},
+	{regexp.MustCompile(`(?i)\b[a-z0-9-]+(\.[a-z0-9-]+)*\.(com|net|org|io|dev|app|ng|co|uk|edu|gov|local|lan|internal|home)\b`), "<host>"},
+}
+
+// redactQuery strips paths, hostnames, addresses and user@host pairs from a
+// query so only the intent is sent to the LLM
+func redactQuery(query string) string {
+	for _, r := range redactions {
+		query = r.pattern.ReplaceAllString(query, r.replacement)
+	}
+	return query
+}
+
+func ensureLLMTables(db *sql.DB) {
+	_, _ = db.Exec(`
+		CREATE TABLE IF NOT EXISTS llm_usage (
+			day TEXT PRIMARY KEY,
+			requests INTEGER NOT NULL DEFAULT 0
+		)
+	`)
+	_, _ = db.Exec(`
+		CREATE TABLE IF NOT EXISTS llm_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sent_text TEXT NOT NULL,
+			sent_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+}
+
+// reserve counts one request against today's (UTC) quota and reports
+// whether it was allowed
+func (o *onlineLLM) reserve() bool {
+	day := time.Now().UTC().Format("2006-01-02")
+	if o.dailyLimit <= 0 {
+		_, _ = o.db.Exec(`
+			INSERT INTO llm_usage (day, requests) VALUES (?, 1)
+			ON CONFLICT(day) DO UPDATE SET requests = requests + 1
+		`, day)
+		return true
+	}
+
+	result, err := o.db.Exec(`
+		INSERT INTO llm_usage (day, requests) VALUES (?, 1)
+		ON CONFLICT(day) DO UPDATE SET requests = requests + 1 WHERE requests < ?
+	`, day, o.dailyLimit)
+	if err != nil {
+		log.Printf("Failed to record LLM usage: %v", err)
+		return false
+	}
+	n, _ := result.RowsAffected()
+	return n > 0
+}
+
+// audit records the exact text sent to the external API
+func (o *onlineLLM) audit(text string) {
+	if _, err := o.db.Exec(`INSERT INTO llm_audit (sent_text) VALUES (?)`, text); err != nil {
+		log.Printf("Failed to write LLM audit log: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+// DependencyNode is one module pulled in, directly or transitively, by a requires entry
+type DependencyNode struct {
+	ID          string `json:"id"`
+	Version     string `json:"version"`
+	RequiredBy  string `json:"required_by"`
+	Requirement string `json:"requirement"` // requires entry; differs from ID when matched through provides
+	Depth       int    `json:"depth"`
+}
+
+// DependencyCycleError reports a circular chain of requires, first module repeated at the end
+type DependencyCycleError struct {
+	Path []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return "circular requires: " + strings.Join(e.Path, " -> ")
+}
+
+// DependencyPlan is the resolved dependency graph of one module
+type DependencyPlan struct {
+	Dependencies []DependencyNode `json:"dependencies"`
+	InstallOrder []string         `json:"install_order"` // dependencies first, the module itself last
+	Missing      []string         `json:"missing"`       // requires entries no visible module satisfies
+}
+
+// resolveDependencies walks requires depth-first from root. lookup maps a
+// requires entry to a module (sql.ErrNoRows when nothing satisfies it).
+func resolveDependencies(root *models.Module, lookup func(requirement string) (*models.Module, error)) (*DependencyPlan, error) {
+	plan := &DependencyPlan{Dependencies: []DependencyNode{}, InstallOrder: []string{}, Missing: []string{}}
+	done := map[string]bool{}
+	missing := map[string]bool{}
+	var stack []string
+
+	var visit func(m *models.Module, depth int) error
+	visit = func(m *models.Module, depth int) error {
+		stack = append(stack, m.Name)
+		for _, req := range m.Requires {
+			req = strings.TrimSpace(req)
+			if req == "" {
+				continue
+			}
+			dep, err := lookup(req)
+			if err == sql.ErrNoRows {
+				if !missing[req] {
+					missing[req] = true
+					plan.Missing = append(plan.Missing, req)
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			for i, name := range stack {
+				if name == dep.Name {
+					path := append(append([]string{}, stack[i:]...), dep.Name)
+					return &DependencyCycleError{Path: path}
+				}
+			}
+			if done[dep.Name] {
+				continue
+			}
+			plan.Dependencies = append(plan.Dependencies, DependencyNode{
+				ID: dep.Name, Version: dep.Version, RequiredBy: m.Name, Requirement: req, Depth: depth,
+			})
+			if err := visit(dep, depth+1); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		done[m.Name] = true
+		plan.InstallOrder = append(plan.InstallOrder, m.Name)
+		return nil
+	}
+
+	if err := visit(root, 1); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// lookupRequirement finds the latest visible module named requirement, or
+// else the most downloaded module that lists it under provides
+func (h *Handlers) lookupRequirement(requirement string) (*models.Module, error) {
+	var name, version string
+	err := h.db.QueryRow(`
+		SELECT name, version FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved'
+		ORDER BY uploaded_at DESC LIMIT 1
+	`, requirement).Scan(&name, &version)
+	if err == sql.ErrNoRows {
+		err = h.db.QueryRow(`
+			SELECT name, version FROM modules
+			WHERE hidden = 0 AND status = 'approved' AND provides LIKE ? ESCAPE '\'
+			ORDER BY downloads DESC, uploaded_at DESC LIMIT 1
+		`, `%"`+likeEscaper.Replace(requirement)+`"%`).Scan(&name, &version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.loadModuleVersion(name, version)
+}
+
+// APIv1ModuleDependencies handles GET /api/v1/modules/:id/dependencies
+// It resolves the module's requires (by module name, then by provides)
+// recursively and returns an install order; circular requires are a 409.
+func (h *Handlers) APIv1ModuleDependencies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
+	moduleID := strings.Split(path, "/")[0]
+
+	var version string
+	err := h.db.QueryRow(`
+		SELECT version FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved'
+		ORDER BY uploaded_at DESC LIMIT 1
+	`, moduleID).Scan(&version)
+	if err == sql.ErrNoRows {
+		writeDiffError(w, http.StatusNotFound, "MODULE_NOT_FOUND", fmt.Sprintf("Module '%s' does not exist", moduleID))
+		return
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	module, err := h.loadModuleVersion(moduleID, version)
+	if err != nil {
+		log.Printf("Failed to load %s v%s: %v", moduleID, version, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	plan, err := resolveDependencies(module, h.lookupRequirement)
+	if cycle, ok := err.(*DependencyCycleError); ok {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    "DEPENDENCY_CYCLE",
+				"message": cycle.Error(),
+				"cycle":   cycle.Path,
+			},
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to resolve dependencies of %s: %v", moduleID, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"module_id":     moduleID,
+		"version":       version,
+		"dependencies":  plan.Dependencies,
+		"install_order": plan.InstallOrder,
+		"missing":       plan.Missing,
+	})
+}
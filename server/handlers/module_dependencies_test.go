@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+func fakeLookup(modules map[string]*models.Module) func(string) (*models.Module, error) {
+	return func(req string) (*models.Module, error) {
+		if m, ok := modules[req]; ok {
+			return m, nil
+		}
+		for _, m := range modules {
+			for _, p := range m.Provides {
+				if p == req {
+					return m, nil
+				}
+			}
+		}
+		return nil, sql.ErrNoRows
+	}
+}
+
+func TestResolveDependencies(t *testing.T) {
+	modules := map[string]*models.Module{
+		"copy_file":         {Name: "copy_file", Requires: []string{"check_file_exists", "disk_checked", "gpu_driver"}},
+		"check_file_exists": {Name: "check_file_exists", Requires: []string{"check_path_exists"}},
+		"check_path_exists": {Name: "check_path_exists"},
+		"check_disk_space":  {Name: "check_disk_space", Provides: []string{"disk_checked"}, Requires: []string{"check_path_exists"}},
+	}
+
+	plan, err := resolveDependencies(modules["copy_file"], fakeLookup(modules))
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	wantOrder := []string{"check_path_exists", "check_file_exists", "check_disk_space", "copy_file"}
+	if !reflect.DeepEqual(plan.InstallOrder, wantOrder) {
+		t.Fatalf("install order = %v, want %v", plan.InstallOrder, wantOrder)
+	}
+	if len(plan.Dependencies) != 3 || plan.Dependencies[1].Depth != 2 || plan.Dependencies[2].Requirement != "disk_checked" {
+		t.Fatalf("unexpected dependencies %+v", plan.Dependencies)
+	}
+	if !reflect.DeepEqual(plan.Missing, []string{"gpu_driver"}) {
+		t.Fatalf("missing = %v", plan.Missing)
+	}
+}
+
+func TestResolveDependenciesCycle(t *testing.T) {
+	modules := map[string]*models.Module{
+		"a": {Name: "a", Requires: []string{"b"}},
+		"b": {Name: "b", Requires: []string{"c"}},
+		"c": {Name: "c", Requires: []string{"a"}},
+	}
+	_, err := resolveDependencies(modules["a"], fakeLookup(modules))
+	var cycle *DependencyCycleError
+	if !errors.As(err, &cycle) || !reflect.DeepEqual(cycle.Path, []string{"a", "b", "c", "a"}) {
+		t.Fatalf("expected cycle a -> b -> c -> a, got %v", err)
+	}
+}
+
+func TestLookupRequirementEscapesLike(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "disk.yaml")
+	if err := os.WriteFile(path, []byte("name: check_disk\nversion: 1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, provides)
+		VALUES ('check_disk', '1.0.0', 'bob', ?, '["disk_checked"]')
+	`, path); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	m, err := h.lookupRequirement("disk_checked")
+	if err != nil || m.Name != "check_disk" {
+		t.Fatalf("lookup disk_checked = %v, %v; want check_disk", m, err)
+	}
+	for _, req := range []string{"disk%", "disk_checke_"} {
+		if m, err := h.lookupRequirement(req); err != sql.ErrNoRows {
+			t.Errorf("lookup %q = %v, %v; want no match", req, m, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	"github.com/themobileprof/clipilot/server/moddiff"
+)
+
+// APIv1ModuleDiff handles GET /api/v1/modules/:id/diff?from=&to=
+// to defaults to the latest version, from to the version uploaded before it.
+func (h *Handlers) APIv1ModuleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
+	moduleID := strings.Split(path, "/")[0]
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+
+	if to == "" {
+		err := h.db.QueryRow("SELECT version FROM modules WHERE name = ? AND status = 'approved' ORDER BY uploaded_at DESC LIMIT 1", moduleID).Scan(&to)
+		if err == sql.ErrNoRows {
+			writeDiffError(w, http.StatusNotFound, "MODULE_NOT_FOUND", fmt.Sprintf("Module '%s' does not exist", moduleID))
+			return
+		}
+		if err != nil {
+			log.Printf("Database error: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	diff, err := h.getModuleDiff(moduleID, from, to)
+	if err == sql.ErrNoRows {
+		writeDiffError(w, http.StatusNotFound, "DIFF_NOT_FOUND",
+			fmt.Sprintf("No diff available for '%s' version %s", moduleID, to))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load module diff: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"module_id": moduleID,
+		"diff":      diff,
+	}); err != nil {
+		log.Printf("Failed to encode diff response: %v", err)
+	}
+}
+
+// getModuleDiff returns the stored diff ending at version to, computing and
+// storing it when both versions exist but no diff was recorded at upload time.
+// An empty from means the version uploaded immediately before to.
+func (h *Handlers) getModuleDiff(name, from, to string) (*moddiff.Diff, error) {
+	query := "SELECT diff_json FROM module_diffs WHERE module_name = ? AND to_version = ?"
+	args := []interface{}{name, to}
+	if from != "" {
+		query += " AND from_version = ?"
+		args = append(args, from)
+	}
+
+	var diffJSON string
+	err := h.db.QueryRow(query+" ORDER BY created_at DESC LIMIT 1", args...).Scan(&diffJSON)
+	if err == nil {
+		var diff moddiff.Diff
+		if err := json.Unmarshal([]byte(diffJSON), &diff); err != nil {
+			return nil, err
+		}
+		return &diff, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	newModule, err := h.loadModuleVersion(name, to)
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		from, err = h.previousVersion(name, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	oldModule, err := h.loadModuleVersion(name, from)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := moddiff.Compare(oldModule, newModule)
+	if err := h.saveModuleDiff(name, diff); err != nil {
+		log.Printf("Warning: failed to store diff for %s %s..%s: %v", name, from, to, err)
+	}
+	return &diff, nil
+}
+
+// recordModuleDiff diffs a freshly uploaded module against the previous
+// version, if any, and stores the result
+func (h *Handlers) recordModuleDiff(module *models.Module) {
+	from, err := h.previousVersion(module.Name, module.Version)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: failed to find previous version of %s: %v", module.Name, err)
+		return
+	}
+	oldModule, err := h.loadModuleVersion(module.Name, from)
+	if err != nil {
+		log.Printf("Warning: failed to load %s v%s for diff: %v", module.Name, from, err)
+		return
+	}
+	if err := h.saveModuleDiff(module.Name, moddiff.Compare(oldModule, module)); err != nil {
+		log.Printf("Warning: failed to store diff for %s v%s: %v", module.Name, module.Version, err)
+	}
+}
+
+// previousVersion returns the version of name uploaded most recently before version
+func (h *Handlers) previousVersion(name, version string) (string, error) {
+	var prev string
+	err := h.db.QueryRow(`
+		SELECT version FROM modules
+		WHERE name = ? AND version != ?
+		  AND uploaded_at <= COALESCE((SELECT uploaded_at FROM modules WHERE name = ? AND version = ?), CURRENT_TIMESTAMP)
+		ORDER BY uploaded_at DESC, id DESC
+		LIMIT 1
+	`, name, version, name, version).Scan(&prev)
+	return prev, err
+}
+
+// loadModuleVersion parses the stored YAML for one module version
+func (h *Handlers) loadModuleVersion(name, version string) (*models.Module, error) {
+	var filePath string
+	if err := h.db.QueryRow("SELECT file_path FROM modules WHERE name = ? AND version = ?", name, version).Scan(&filePath); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var module models.Module
+	if err := yaml.Unmarshal(content, &module); err != nil {
+		return nil, err
+	}
+	if module.Version == "" {
+		module.Version = version
+	}
+	return &module, nil
+}
+
+func (h *Handlers) saveModuleDiff(name string, diff moddiff.Diff) error {
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	_, err = h.db.Exec(`
+		INSERT INTO module_diffs (module_name, from_version, to_version, diff_json)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(module_name, from_version, to_version) DO UPDATE SET
+			diff_json = excluded.diff_json,
+			created_at = CURRENT_TIMESTAMP
+	`, name, diff.FromVersion, diff.ToVersion, string(diffJSON))
+	return err
+}
+
+func writeDiffError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+	}); err != nil {
+		log.Printf("Failed to encode error response: %v", err)
+	}
+}
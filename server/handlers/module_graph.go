@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/themobileprof/clipilot/server/flowgraph"
+)
+
+// APIv1ModuleGraph handles GET /api/v1/modules/:id/graph?version=
+// It returns the module's flows as a Graphviz DOT graph (latest version by
+// default) so steps, branches and sub-module calls can be reviewed before running.
+func (h *Handlers) APIv1ModuleGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
+	moduleID := strings.Split(path, "/")[0]
+	version := r.URL.Query().Get("version")
+
+	query := "SELECT version FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved'"
+	args := []interface{}{moduleID}
+	if version != "" {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+	err := h.db.QueryRow(query+" ORDER BY uploaded_at DESC LIMIT 1", args...).Scan(&version)
+	if err == sql.ErrNoRows {
+		writeDiffError(w, http.StatusNotFound, "MODULE_NOT_FOUND", fmt.Sprintf("Module '%s' does not exist", moduleID))
+		return
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	module, err := h.loadModuleVersion(moduleID, version)
+	if err != nil {
+		log.Printf("Failed to load %s v%s for graph: %v", moduleID, version, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	fmt.Fprint(w, flowgraph.DOT(module))
+}
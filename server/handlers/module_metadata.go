@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	"github.com/themobileprof/clipilot/server/scanner"
+)
+
+// ModuleVersionInfo describes one uploaded version of a module
+type ModuleVersionInfo struct {
+	ID          int64  `json:"id"`
+	Version     string `json:"version"`
+	UploadedAt  string `json:"uploaded_at"`
+	Checksum    string `json:"checksum_sha256"`
+	DownloadURL string `json:"download_url"`
+}
+
+// FlowSummary is a compact overview of one flow in a module
+type FlowSummary struct {
+	Name     string `json:"name"`
+	Start    string `json:"start"`
+	Steps    int    `json:"steps"`
+	Commands int    `json:"commands"`
+}
+
+// APIGetModule handles GET /api/modules/:id
+// :id may be the numeric record ID or the module name (latest version).
+// Raw YAML is served separately at /api/modules/:id/download.
+func (h *Handlers) APIGetModule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/modules/"), "/")
+	parts := strings.Split(path, "/")
+	if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "download") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var m ModuleRecord
+	var tagsJSON, riskJSON string
+	query := `
+		SELECT id, name, version, COALESCE(description, ''), COALESCE(author, ''), COALESCE(tags, '[]'),
+		       uploaded_at, uploaded_by, file_path, downloads, COALESCE(risk_level, 'low'), COALESCE(checksum, ''),
+		       COALESCE(risk_report, '')
+		FROM modules
+	`
+	var err error
+	if numericID, convErr := strconv.ParseInt(parts[0], 10, 64); convErr == nil {
+		err = h.db.QueryRow(query+" WHERE id = ? AND hidden = 0 AND status = 'approved'", numericID).Scan(&m.ID, &m.Name, &m.Version, &m.Description,
+			&m.Author, &tagsJSON, &m.UploadedAt, &m.UploadedBy, &m.FilePath, &m.Downloads, &m.RiskLevel, &m.Checksum, &riskJSON)
+	} else {
+		err = h.db.QueryRow(query+" WHERE name = ? AND hidden = 0 AND status = 'approved' ORDER BY uploaded_at DESC LIMIT 1", parts[0]).Scan(&m.ID, &m.Name,
+			&m.Version, &m.Description, &m.Author, &tagsJSON, &m.UploadedAt, &m.UploadedBy, &m.FilePath, &m.Downloads, &m.RiskLevel, &m.Checksum, &riskJSON)
+	}
+
+	if err == sql.ErrNoRows {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]string{
+				"code":    "MODULE_NOT_FOUND",
+				"message": fmt.Sprintf("Module '%s' does not exist", parts[0]),
+			},
+		}); err != nil {
+			log.Printf("Failed to encode error response: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(parts) == 2 {
+		h.serveModuleFile(w, r, m)
+		return
+	}
+
+	var tagsList []string
+	_ = json.Unmarshal([]byte(tagsJSON), &tagsList)
+	riskReport := scanner.Report{Level: m.RiskLevel, Findings: []scanner.Finding{}}
+	_ = json.Unmarshal([]byte(riskJSON), &riskReport)
+
+	versions, err := h.moduleVersions(m.Name)
+	if err != nil {
+		log.Printf("Failed to load module versions: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	checksum := m.Checksum
+	var flows []FlowSummary
+	hasUninstall := false
+	privileged := []string{}
+	if content, err := os.ReadFile(m.FilePath); err == nil {
+		if checksum == "" {
+			checksum = checksumOf(content)
+		}
+		var module models.Module
+		if err := yaml.Unmarshal(content, &module); err == nil {
+			flows = summarizeFlows(&module)
+			hasUninstall = hasUninstallFlow(&module)
+			privileged = rootSteps(&module)
+		}
+	}
+
+	// Changes since the previous version, if one exists
+	var changes interface{}
+	if diff, err := h.getModuleDiff(m.Name, "", m.Version); err == nil {
+		changes = diff
+	} else if err != sql.ErrNoRows {
+		log.Printf("Failed to load module diff: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"id":              m.ID,
+		"name":            m.Name,
+		"version":         m.Version,
+		"description":     m.Description,
+		"author":          m.Author,
+		"tags":            tagsList,
+		"downloads":       m.Downloads,
+		"uploaded_by":     m.UploadedBy,
+		"uploaded_at":     m.UploadedAt.Format(time.RFC3339),
+		"checksum_sha256": checksum,
+		"risk_level":      m.RiskLevel,
+		"risk_report":     riskReport,
+		"versions":        versions,
+		"flows":           flows,
+		"has_uninstall":   hasUninstall,
+		"requires_root":   len(privileged) > 0,
+		"root_steps":      privileged,
+		"changes":         changes,
+		"download_urls": map[string]string{
+			"yaml":   fmt.Sprintf("/api/modules/%d/download", m.ID),
+			"latest": fmt.Sprintf("/api/v1/modules/%s/download", m.Name),
+		},
+	}
+
+	writeCachedJSON(w, r, response)
+}
+
+// serveModuleFile streams the YAML for a module record and counts the download
+func (h *Handlers) serveModuleFile(w http.ResponseWriter, r *http.Request, m ModuleRecord) {
+	_, _ = h.db.Exec("UPDATE modules SET downloads = downloads + 1 WHERE id = ?", m.ID)
+	h.recordDownload(r, m.Name, m.Version)
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.yaml", m.Name, m.Version))
+	if m.Checksum != "" {
+		w.Header().Set("X-Checksum-SHA256", m.Checksum)
+	}
+	w.Header().Set("X-Risk-Level", m.RiskLevel)
+	http.ServeFile(w, r, m.FilePath)
+}
+
+// moduleVersions lists every uploaded version of a module, newest first
+func (h *Handlers) moduleVersions(name string) ([]ModuleVersionInfo, error) {
+	rows, err := h.db.Query(`
+		SELECT id, version, uploaded_at, file_path, COALESCE(checksum, '')
+		FROM modules
+		WHERE name = ? AND status = 'approved'
+		ORDER BY uploaded_at DESC
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []ModuleVersionInfo{}
+	for rows.Next() {
+		var v ModuleVersionInfo
+		var uploadedAt time.Time
+		var filePath string
+		if err := rows.Scan(&v.ID, &v.Version, &uploadedAt, &filePath, &v.Checksum); err != nil {
+			log.Printf("Scan error: %v", err)
+			continue
+		}
+		v.UploadedAt = uploadedAt.Format(time.RFC3339)
+		if v.Checksum == "" {
+			if content, err := os.ReadFile(filePath); err == nil {
+				v.Checksum = checksumOf(content)
+			}
+		}
+		v.DownloadURL = fmt.Sprintf("/api/modules/%d/download", v.ID)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// summarizeFlows returns a per-flow overview sorted by flow name
+func summarizeFlows(module *models.Module) []FlowSummary {
+	summaries := []FlowSummary{}
+	for name, flow := range module.Flows {
+		if flow == nil {
+			continue
+		}
+		s := FlowSummary{Name: name, Start: flow.Start, Steps: len(flow.Steps)}
+		for _, step := range flow.Steps {
+			if step != nil && step.Command != "" {
+				s.Commands++
+			}
+		}
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// hasUninstallFlow reports whether a module ships the conventional uninstall flow
+func hasUninstallFlow(module *models.Module) bool {
+	flow, ok := module.Flows[models.UninstallFlow]
+	return ok && flow != nil && len(flow.Steps) > 0
+}
+
+// privilegedCommandRegex matches commands that escalate to root
+var privilegedCommandRegex = regexp.MustCompile(`(^|[\s;&|(])(sudo|doas|pkexec|su\s+(-\s+)?(root\s+)?-c)(\s|$)`)
+
+// rootSteps lists the "flow/step" keys whose command needs root, sorted, so
+// Clio can warn before confirmation and honour allow_sudo: false
+func rootSteps(module *models.Module) []string {
+	steps := []string{}
+	for flowName, flow := range module.Flows {
+		if flow == nil {
+			continue
+		}
+		for key, step := range flow.Steps {
+			if step != nil && privilegedCommandRegex.MatchString(step.Command) {
+				steps = append(steps, flowName+"/"+key)
+			}
+		}
+	}
+	sort.Strings(steps)
+	return steps
+}
+
+// checksumOf returns the hex SHA-256 recorded as a module file's checksum
+func checksumOf(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// forEachModuleFile calls fn with the ID and file contents of every module
+// matching the where clause. IDs are collected before any file is read so fn
+// may update the modules table; unreadable files are logged and skipped.
+func forEachModuleFile(db *sql.DB, where string, fn func(id int64, content []byte) error) error {
+	rows, err := db.Query("SELECT id, file_path FROM modules WHERE " + where)
+	if err != nil {
+		return err
+	}
+	pending := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var filePath string
+		if err := rows.Scan(&id, &filePath); err != nil {
+			rows.Close()
+			return err
+		}
+		pending[id] = filePath
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, filePath := range pending {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			log.Printf("Warning: cannot read module %d: %v", id, err)
+			continue
+		}
+		if err := fn(id, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillChecksums records the SHA-256 of modules uploaded before checksums
+// were stored at upload time
+func backfillChecksums(db *sql.DB) error {
+	return forEachModuleFile(db, "checksum IS NULL OR checksum = ''", func(id int64, content []byte) error {
+		_, err := db.Exec("UPDATE modules SET checksum = ? WHERE id = ?", checksumOf(content), id)
+		return err
+	})
+}
+
+// backfillRiskReports scans modules uploaded before the full scanner report
+// was stored, refreshing their risk level with the current rules
+func backfillRiskReports(db *sql.DB) error {
+	return forEachModuleFile(db, "risk_report IS NULL OR risk_report = ''", func(id int64, content []byte) error {
+		var module models.Module
+		if err := yaml.Unmarshal(content, &module); err != nil {
+			log.Printf("Warning: cannot parse module %d for scanning: %v", id, err)
+			return nil
+		}
+		report := scanner.ScanModule(&module)
+		reportJSON, _ := json.Marshal(report)
+		_, err := db.Exec("UPDATE modules SET risk_level = ?, risk_report = ? WHERE id = ?", report.Level, string(reportJSON), id)
+		return err
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	"github.com/themobileprof/clipilot/server/migrations"
+	"github.com/themobileprof/clipilot/server/scanner"
+	_ "modernc.org/sqlite"
+)
+
+func TestRootSteps(t *testing.T) {
+	module := &models.Module{Flows: map[string]*models.Flow{
+		"main": {Steps: map[string]*models.Step{
+			"update":  {Type: "action", Command: "sudo apt-get update"},
+			"piped":   {Type: "action", Command: "echo x | sudo tee /etc/x"},
+			"su":      {Type: "action", Command: "su -c 'systemctl restart nginx'"},
+			"pseudo":  {Type: "action", Command: "pseudocode --sudo-less"},
+			"message": {Type: "instruction", Message: "Run sudo yourself"},
+		}},
+	}}
+	got := rootSteps(module)
+	want := []string{"main/piped", "main/su", "main/update"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rootSteps = %v, want %v", got, want)
+	}
+}
+
+// openTestDB returns an in-memory registry database with the full schema
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	schema, err := migrations.GetInitialSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations.EnsureChangeTriggers(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAPIGetModuleETagTracksMetadata(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "demo.yaml")
+	if err := os.WriteFile(path, []byte("name: demo\nversion: 1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path, checksum) VALUES ('demo', '1.0.0', 'bob', ?, 'abc')`, path); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/modules/demo", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h.APIGetModule(w, req)
+		return w
+	}
+
+	etag := get("").Header().Get("ETag")
+	if etag == "" || etag == `""` {
+		t.Fatalf("missing ETag %q", etag)
+	}
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("status %d for unchanged metadata, want 304", w.Code)
+	}
+	if _, err := db.Exec("UPDATE modules SET downloads = 5"); err != nil {
+		t.Fatal(err)
+	}
+	if w := get(etag); w.Code != http.StatusOK {
+		t.Fatalf("status %d after the download count changed, want 200", w.Code)
+	}
+}
+
+func TestBackfillRiskReports(t *testing.T) {
+	db := openTestDB(t)
+
+	path := filepath.Join(t.TempDir(), "installer.yaml")
+	yamlContent := `name: installer
+version: 1.0.0
+flows:
+  main:
+    start: fetch
+    steps:
+      fetch:
+        type: action
+        command: curl -fsSL https://example.com/install.sh | sh
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path) VALUES ('installer', '1.0.0', 'bob', ?)`, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backfillRiskReports(db); err != nil {
+		t.Fatal(err)
+	}
+	var level, reportJSON string
+	if err := db.QueryRow("SELECT risk_level, risk_report FROM modules WHERE name = 'installer'").Scan(&level, &reportJSON); err != nil {
+		t.Fatal(err)
+	}
+	var report scanner.Report
+	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+		t.Fatal(err)
+	}
+	if level != scanner.RiskMedium || len(report.Findings) != 1 || report.Findings[0].Rule != "pipe-to-shell" {
+		t.Fatalf("level %s, report %+v", level, report)
+	}
+}
+
+func TestBackfillChecksums(t *testing.T) {
+	db := openTestDB(t)
+	content := []byte("name: demo\nversion: 1.0.0\n")
+	path := filepath.Join(t.TempDir(), "demo.yaml")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, checksum) VALUES
+			('demo', '1.0.0', 'bob', ?, NULL),
+			('gone', '1.0.0', 'bob', '/nonexistent/gone.yaml', ''),
+			('kept', '1.0.0', 'bob', '/nonexistent/kept.yaml', 'abc')
+	`, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backfillChecksums(db); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	rows, err := db.Query("SELECT name, COALESCE(checksum, '') FROM modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			t.Fatal(err)
+		}
+		got[name] = checksum
+	}
+	want := map[string]string{"demo": checksumOf(content), "gone": "", "kept": "abc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("checksums = %v, want %v", got, want)
+	}
+}
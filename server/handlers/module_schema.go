@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/themobileprof/clipilot/server/schema"
+)
+
+// APIv1ModuleSchema handles GET /api/v1/schema/module.json
+// Editors point yaml-language-server at it for completion while authoring modules.
+func (h *Handlers) APIv1ModuleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema.Module()); err != nil {
+		log.Printf("Failed to encode module schema: %v", err)
+	}
+}
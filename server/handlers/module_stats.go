@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ModuleRunStats aggregates opt-in execution reports for a module
+type ModuleRunStats struct {
+	Runs              int     `json:"runs"`
+	SuccessRate       float64 `json:"success_rate"`
+	AvgDurationMs     int64   `json:"avg_duration_ms"`
+	LastFailureReason string  `json:"last_failure_reason,omitempty"`
+
+	// LastFailureEnvironment is the environment snapshot sent with the most
+	// recent failed run, for reproducing it
+	LastFailureEnvironment map[string]string `json:"last_failure_environment,omitempty"`
+
+	FailingValidations []ValidationFailure `json:"failing_validations,omitempty"`
+}
+
+// ValidationResult is one validation outcome in a run report
+type ValidationResult struct {
+	Step     string `json:"step"`
+	Command  string `json:"command"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Passed   bool   `json:"passed"`
+	WarnOnly bool   `json:"warn_only,omitempty"`
+}
+
+// ValidationFailure counts how often one validation check failed across runs
+type ValidationFailure struct {
+	Step     string `json:"step"`
+	Command  string `json:"command"`
+	Failures int    `json:"failures"`
+	WarnOnly bool   `json:"warn_only"`
+}
+
+// maxValidationResults caps how many validation outcomes one run report may carry
+const maxValidationResults = 50
+
+// maxEnvironmentFacts caps how many environment facts one run report may carry
+const maxEnvironmentFacts = 50
+
+// maxRunReportsPerDay caps how many run reports one client IP can send for a
+// module in 24 hours, so a single client cannot rewrite its success rate
+const maxRunReportsPerDay = 20
+
+// maxRunReportBytes caps the size of a run report body
+const maxRunReportBytes = 256 << 10
+
+// loadReporterKey reads the HMAC key used to pseudonymise run reporters,
+// creating a random one on first start
+func loadReporterKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil && len(key) >= 32 {
+		return key, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// reporterID identifies the client sending a run report without storing its
+// IP: an unsalted hash of an IPv4 address is trivial to reverse
+func (h *Handlers) reporterID(r *http.Request) string {
+	mac := hmac.New(sha256.New, h.reporterKey)
+	mac.Write([]byte(peerIP(r)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// peerIP returns the address of the client that connected. X-Forwarded-For is
+// only honoured when the connection comes from a local reverse proxy, and then
+// only its last entry, which the proxy appended itself; earlier entries are
+// whatever the client chose to send.
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !(ip.IsLoopback() || ip.IsPrivate()) {
+		return host
+	}
+	xff := r.Header.Get("X-Forwarded-For")
+	if xff == "" {
+		return host
+	}
+	entries := strings.Split(xff, ",")
+	if last := strings.TrimSpace(entries[len(entries)-1]); net.ParseIP(last) != nil {
+		return last
+	}
+	return host
+}
+
+// APIv1ModuleStats handles /api/v1/modules/:id/stats
+// POST records a single run report from Clio (opt-in, at most
+// maxRunReportsPerDay per client IP and module), GET returns the aggregate
+// along with downloads broken down by client platform.
+func (h *Handlers) APIv1ModuleStats(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/")
+	moduleID := strings.Split(path, "/")[0]
+
+	var exists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM modules WHERE name = ? AND hidden = 0 AND status = 'approved')", moduleID).Scan(&exists)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Module not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		stats, err := getModuleRunStats(h.db, moduleID)
+		if err != nil {
+			log.Printf("Failed to load module stats: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		platforms, err := h.downloadsByPlatform(moduleID)
+		if err != nil {
+			log.Printf("Failed to load download platforms: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"module_id":             moduleID,
+			"stats":                 stats,
+			"downloads_by_platform": platforms,
+		}); err != nil {
+			log.Printf("Failed to encode stats response: %v", err)
+		}
+
+	case http.MethodPost:
+		var report struct {
+			Version       string `json:"version"`
+			Success       bool   `json:"success"`
+			DurationMs    int64  `json:"duration_ms"`
+			FailureReason string `json:"failure_reason,omitempty"`
+			Platform      string `json:"platform,omitempty"`
+
+			Validations []ValidationResult `json:"validations,omitempty"`
+
+			// Environment is the snapshot Clio takes at flow start: distro,
+			// versions of declared tools, free disk and so on
+			Environment map[string]string `json:"environment,omitempty"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRunReportBytes)).Decode(&report); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if report.DurationMs < 0 {
+			report.DurationMs = 0
+		}
+		report.Version = truncate(report.Version, 50)
+		report.Platform = truncate(report.Platform, 64)
+		report.FailureReason = truncate(report.FailureReason, 500)
+		if len(report.Validations) > maxValidationResults {
+			http.Error(w, "Too many validation results", http.StatusBadRequest)
+			return
+		}
+		if len(report.Environment) > maxEnvironmentFacts {
+			http.Error(w, "Too many environment facts", http.StatusBadRequest)
+			return
+		}
+
+		reporter := h.reporterID(r)
+		var recent int
+		if err := h.db.QueryRow(`
+			SELECT COUNT(*) FROM module_run_stats
+			WHERE module_name = ? AND reporter = ? AND reported_at > datetime('now', '-1 day')
+		`, moduleID, reporter).Scan(&recent); err != nil {
+			log.Printf("Failed to count run reports: %v", err)
+			http.Error(w, "Failed to save report", http.StatusInternalServerError)
+			return
+		}
+		if recent >= maxRunReportsPerDay {
+			http.Error(w, "Too many run reports for this module today", http.StatusTooManyRequests)
+			return
+		}
+
+		if err := recordModuleRun(h.db, moduleID, report.Version, report.Success, report.DurationMs,
+			report.FailureReason, report.Platform, reporter, report.Validations, report.Environment); err != nil {
+			log.Printf("Failed to record module run: %v", err)
+			http.Error(w, "Failed to save report", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+		}); err != nil {
+			log.Printf("Failed to encode response: %v", err)
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// recordModuleRun stores one run report, its validation outcomes and its
+// environment snapshot
+func recordModuleRun(db *sql.DB, moduleName, version string, success bool, durationMs int64,
+	failureReason, platform, reporter string, validations []ValidationResult, environment map[string]string) error {
+	var environmentJSON sql.NullString
+	if len(environment) > 0 {
+		facts := make(map[string]string, len(environment))
+		for k, v := range environment {
+			facts[truncate(k, 100)] = truncate(v, 200)
+		}
+		data, err := json.Marshal(facts)
+		if err != nil {
+			return err
+		}
+		environmentJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		INSERT INTO module_run_stats (module_name, module_version, success, duration_ms, failure_reason, platform, environment, reporter)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, moduleName, version, success, durationMs, failureReason, platform, environmentJSON, reporter)
+	if err != nil {
+		return err
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, v := range validations {
+		if v.Step == "" {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO module_validation_results (run_id, module_name, step, check_command, expected, actual, passed, warn_only)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, runID, moduleName, truncate(v.Step, 100), truncate(v.Command, 500), truncate(v.Expected, 500),
+			truncate(v.Actual, 500), v.Passed, v.WarnOnly); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// truncate shortens s to at most n runes, never splitting a UTF-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// getModuleRunStats aggregates all run reports for a module name
+func getModuleRunStats(db *sql.DB, moduleName string) (ModuleRunStats, error) {
+	var stats ModuleRunStats
+	var successes int
+	var avgDuration sql.NullFloat64
+
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(success), 0), AVG(duration_ms)
+		FROM module_run_stats
+		WHERE module_name = ?
+	`, moduleName).Scan(&stats.Runs, &successes, &avgDuration)
+	if err != nil {
+		return stats, err
+	}
+
+	if stats.Runs > 0 {
+		stats.SuccessRate = float64(successes) / float64(stats.Runs) * 100
+	}
+	if avgDuration.Valid {
+		stats.AvgDurationMs = int64(avgDuration.Float64)
+	}
+
+	var environmentJSON string
+	err = db.QueryRow(`
+		SELECT COALESCE(failure_reason, ''), COALESCE(environment, '')
+		FROM module_run_stats
+		WHERE module_name = ? AND success = 0
+		ORDER BY reported_at DESC, id DESC
+		LIMIT 1
+	`, moduleName).Scan(&stats.LastFailureReason, &environmentJSON)
+	if err != nil && err != sql.ErrNoRows {
+		return stats, err
+	}
+	if environmentJSON != "" {
+		if err := json.Unmarshal([]byte(environmentJSON), &stats.LastFailureEnvironment); err != nil {
+			log.Printf("Warning: bad environment snapshot for %s: %v", moduleName, err)
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT step, COALESCE(check_command, ''), COUNT(*), MAX(warn_only)
+		FROM module_validation_results
+		WHERE module_name = ? AND passed = 0
+		GROUP BY step, check_command
+		ORDER BY COUNT(*) DESC, step
+		LIMIT 5
+	`, moduleName)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f ValidationFailure
+		if err := rows.Scan(&f.Step, &f.Command, &f.Failures, &f.WarnOnly); err != nil {
+			return stats, err
+		}
+		stats.FailingValidations = append(stats.FailingValidations, f)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func postRunReport(h *Handlers, module, ip, body string) int {
+	return postRunReportVia(h, module, ip, "", body)
+}
+
+// postRunReportVia posts a run report from ip carrying an X-Forwarded-For header
+func postRunReportVia(h *Handlers, module, ip, forwardedFor, body string) int {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/modules/"+module+"/stats", strings.NewReader(body))
+	r.RemoteAddr = ip + ":4000"
+	if forwardedFor != "" {
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	h.APIv1ModuleStats(w, r)
+	return w.Code
+}
+
+func TestAPIv1ModuleStatsAggregates(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path) VALUES ('demo', '1.0.0', 'bob', '/dev/null')`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	reports := []string{
+		`{"version":"1.0.0","success":true,"duration_ms":1000}`,
+		`{"version":"1.0.0","success":true,"duration_ms":3000}`,
+		`{"version":"1.0.0","success":false,"duration_ms":2000,"failure_reason":"apt locked",
+		  "environment":{"distro":"ubuntu-22.04"},
+		  "validations":[{"step":"install","command":"which nginx","passed":false},{"step":"start","command":"systemctl is-active nginx","passed":true}]}`,
+		`{"version":"1.0.0","success":false,"duration_ms":-5,"failure_reason":"disk full",
+		  "environment":{"distro":"debian-12","free_disk":"10M"},
+		  "validations":[{"step":"install","command":"which nginx","passed":false}]}`,
+	}
+	for _, body := range reports {
+		if code := postRunReport(h, "demo", "10.0.0.1", body); code != http.StatusCreated {
+			t.Fatalf("POST %s = %d, want 201", body, code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.APIv1ModuleStats(w, httptest.NewRequest(http.MethodGet, "/api/v1/modules/demo/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET = %d, want 200", w.Code)
+	}
+	var resp struct {
+		Stats ModuleRunStats `json:"stats"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	stats := resp.Stats
+	if stats.Runs != 4 || stats.SuccessRate != 50 || stats.AvgDurationMs != 1500 {
+		t.Errorf("runs/rate/avg = %d/%v/%d, want 4/50/1500", stats.Runs, stats.SuccessRate, stats.AvgDurationMs)
+	}
+	if stats.LastFailureReason != "disk full" {
+		t.Errorf("LastFailureReason = %q, want disk full", stats.LastFailureReason)
+	}
+	if got := stats.LastFailureEnvironment; got["distro"] != "debian-12" || got["free_disk"] != "10M" {
+		t.Errorf("LastFailureEnvironment = %v, want the debian-12 snapshot", got)
+	}
+	if len(stats.FailingValidations) != 1 || stats.FailingValidations[0].Step != "install" || stats.FailingValidations[0].Failures != 2 {
+		t.Errorf("FailingValidations = %+v, want install failing twice", stats.FailingValidations)
+	}
+}
+
+func TestAPIv1ModuleStatsRejects(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, hidden, status) VALUES
+			('demo', '1.0.0', 'bob', '/dev/null', 0, 'approved'),
+			('hidden_mod', '1.0.0', 'bob', '/dev/null', 1, 'approved'),
+			('pending_mod', '1.0.0', 'bob', '/dev/null', 0, 'pending')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	tooManyFacts := make([]string, maxEnvironmentFacts+1)
+	for i := range tooManyFacts {
+		tooManyFacts[i] = fmt.Sprintf(`"k%d":"v"`, i)
+	}
+	tooManyValidations := strings.TrimSuffix(strings.Repeat(`{"step":"s","passed":true},`, maxValidationResults+1), ",")
+	oversized := `{"failure_reason":"` + strings.Repeat("x", maxRunReportBytes) + `"}`
+
+	tests := []struct {
+		name   string
+		module string
+		body   string
+		want   int
+	}{
+		{"unknown module", "missing", `{"success":true}`, http.StatusNotFound},
+		{"hidden module", "hidden_mod", `{"success":true}`, http.StatusNotFound},
+		{"pending module", "pending_mod", `{"success":true}`, http.StatusNotFound},
+		{"bad json", "demo", `{`, http.StatusBadRequest},
+		{"too many environment facts", "demo", `{"environment":{` + strings.Join(tooManyFacts, ",") + `}}`, http.StatusBadRequest},
+		{"too many validations", "demo", `{"validations":[` + tooManyValidations + `]}`, http.StatusBadRequest},
+		{"oversized body", "demo", oversized, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postRunReport(h, tt.module, "10.0.0.1", tt.body); got != tt.want {
+				t.Errorf("POST = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIv1ModuleStatsQuota(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path) VALUES ('demo', '1.0.0', 'bob', '/dev/null')`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+
+	for i := 0; i < maxRunReportsPerDay; i++ {
+		if code := postRunReport(h, "demo", "10.0.0.1", `{"success":false}`); code != http.StatusCreated {
+			t.Fatalf("report %d = %d, want 201", i, code)
+		}
+	}
+	if code := postRunReport(h, "demo", "10.0.0.1", `{"success":false}`); code != http.StatusTooManyRequests {
+		t.Fatalf("report over quota = %d, want 429", code)
+	}
+	if code := postRunReport(h, "demo", "10.0.0.2", `{"success":true}`); code != http.StatusCreated {
+		t.Fatalf("report from another client = %d, want 201", code)
+	}
+
+	// A direct client cannot dodge the quota by rotating X-Forwarded-For
+	for i := 0; i < maxRunReportsPerDay; i++ {
+		postRunReportVia(h, "demo", "203.0.113.7", fmt.Sprintf("198.51.100.%d", i), `{"success":true}`)
+	}
+	if code := postRunReportVia(h, "demo", "203.0.113.7", "198.51.100.99", `{"success":true}`); code != http.StatusTooManyRequests {
+		t.Fatalf("report with a rotated X-Forwarded-For = %d, want 429", code)
+	}
+}
+
+func TestPeerIP(t *testing.T) {
+	cases := []struct {
+		remote, forwardedFor, want string
+	}{
+		{"203.0.113.7:4000", "", "203.0.113.7"},
+		{"203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"127.0.0.1:4000", "198.51.100.1", "198.51.100.1"},
+		{"10.0.0.5:4000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"127.0.0.1:4000", "not-an-ip", "127.0.0.1"},
+		{"[::1]:4000", "", "::1"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = c.remote
+		if c.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", c.forwardedFor)
+		}
+		if got := peerIP(r); got != c.want {
+			t.Errorf("peerIP(%q, %q) = %q, want %q", c.remote, c.forwardedFor, got, c.want)
+		}
+	}
+}
+
+func TestReporterIDIsKeyed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reporter.key")
+	key, err := loadReporterKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := loadReporterKey(path); err != nil || string(again) != string(key) {
+		t.Fatalf("reloaded key differs (err %v)", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "203.0.113.7:4000"
+	id := (&Handlers{reporterKey: key}).reporterID(r)
+	if id == fmt.Sprintf("%x", sha256.Sum256([]byte("203.0.113.7"))) {
+		t.Fatal("reporter ID is a plain hash of the IP")
+	}
+	if other := (&Handlers{reporterKey: []byte("another key")}).reporterID(r); other == id {
+		t.Fatal("reporter ID does not depend on the key")
+	}
+}
+
+func TestRunReportEnvironmentSnapshot(t *testing.T) {
+	db := openTestDB(t)
+	long := strings.Repeat("x", 300)
+	if err := recordModuleRun(db, "demo", "1.0.0", false, 10, "apt locked", "", "ip",
+		nil, map[string]string{"distro": "ubuntu-22.04", "kernel": long}); err != nil {
+		t.Fatal(err)
+	}
+	// A later successful run must not replace the failure's snapshot
+	if err := recordModuleRun(db, "demo", "1.0.0", true, 10, "", "", "ip",
+		nil, map[string]string{"distro": "fedora-40"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := getModuleRunStats(db, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := stats.LastFailureEnvironment
+	if env["distro"] != "ubuntu-22.04" {
+		t.Errorf("distro = %q, want the failed run's ubuntu-22.04", env["distro"])
+	}
+	if len(env["kernel"]) != 200 {
+		t.Errorf("kernel fact is %d bytes, want it truncated to 200", len(env["kernel"]))
+	}
+
+	if err := recordModuleRun(db, "clean", "1.0.0", false, 10, "", "", "ip", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := getModuleRunStats(db, "clean"); err != nil || stats.LastFailureEnvironment != nil {
+		t.Errorf("run without snapshot: env = %v, err = %v", stats.LastFailureEnvironment, err)
+	}
+}
+
+func TestFailingValidationsKeepWarnOnly(t *testing.T) {
+	db := openTestDB(t)
+	results := []ValidationResult{
+		{Step: "index", Command: "test -f ~/.cache/index", Passed: false, WarnOnly: true},
+		{Step: "serve", Command: "curl -sf localhost:8000", Passed: false},
+	}
+	if err := recordModuleRun(db, "demo", "1.0.0", true, 10, "", "", "ip", results, nil); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := getModuleRunStats(db, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnOnly := map[string]bool{}
+	for _, f := range stats.FailingValidations {
+		warnOnly[f.Step] = f.WarnOnly
+	}
+	if len(warnOnly) != 2 || !warnOnly["index"] || warnOnly["serve"] {
+		t.Fatalf("failing validations = %+v, want index as warn_only and serve as critical", stats.FailingValidations)
+	}
+}
+
+func TestTruncateKeepsRunes(t *testing.T) {
+	s := strings.Repeat("é", 10)
+	got := truncate(s, 5)
+	if got != strings.Repeat("é", 5) || !utf8.ValidString(got) {
+		t.Errorf("truncate = %q, want five runes", got)
+	}
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate = %q, want short", got)
+	}
+}
+
+func TestRunReportTruncatesFields(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path) VALUES ('demo', '1.0.0', 'bob', '/dev/null')`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db}
+	body := fmt.Sprintf(`{"version":%q,"platform":%q,"success":true}`, strings.Repeat("9", 500), strings.Repeat("é", 500))
+	if code := postRunReport(h, "demo", "10.0.0.1", body); code != http.StatusCreated {
+		t.Fatalf("POST = %d, want 201", code)
+	}
+	var version, platform string
+	if err := db.QueryRow("SELECT module_version, platform FROM module_run_stats").Scan(&version, &platform); err != nil {
+		t.Fatal(err)
+	}
+	if len(version) != 50 || utf8.RuneCountInString(platform) != 64 || !utf8.ValidString(platform) {
+		t.Fatalf("stored version of %d bytes and platform of %d runes, want 50 and 64", len(version), utf8.RuneCountInString(platform))
+	}
+}
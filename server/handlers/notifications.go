@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/themobileprof/clipilot/server/notify"
+)
+
+// notify delivers an event in the background so slow mail servers or
+// webhooks never hold up a request
+func (h *Handlers) notify(ev notify.Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	go func() {
+		if err := h.notifier.Notify(ev); err != nil {
+			log.Printf("Warning: failed to send %s notification: %v", ev.Type, err)
+		}
+	}()
+}
+
+// moduleOwnerEmails returns the email addresses of everyone who uploaded a version of a module
+func (h *Handlers) moduleOwnerEmails(name string) []string {
+	rows, err := h.db.Query(`
+		SELECT DISTINCT u.email
+		FROM modules m
+		JOIN users u ON u.username = m.uploaded_by
+		WHERE m.name = ? AND u.email != ''
+	`, name)
+	if err != nil {
+		log.Printf("Failed to look up owners of %s: %v", name, err)
+		return nil
+	}
+	defer rows.Close()
+
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err == nil {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// userEmails returns the email address of a registered user, or nothing for
+// an empty or unknown username
+func (h *Handlers) userEmails(username string) []string {
+	if username == "" {
+		return nil
+	}
+	var email string
+	err := h.db.QueryRow("SELECT email FROM users WHERE username = ? AND email != ''", username).Scan(&email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to look up email of %s: %v", username, err)
+		}
+		return nil
+	}
+	return []string{email}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/themobileprof/clipilot/server/notify"
+)
+
+// validReportReasons are the reasons a user can pick when flagging a module
+var validReportReasons = map[string]bool{
+	"malicious": true,
+	"broken":    true,
+	"spam":      true,
+	"other":     true,
+}
+
+// ReportedModule groups the open reports against one module for the admin queue
+type ReportedModule struct {
+	Name     string
+	Hidden   bool
+	Reports  []ModuleReport
+	LastSeen time.Time
+}
+
+// ModuleReport is a single user report
+type ModuleReport struct {
+	ID        int64
+	Reporter  string
+	Reason    string
+	Details   string
+	CreatedAt time.Time
+}
+
+// APIReportModule handles POST /api/modules/report (form: module, reason, details).
+// Each user can hold one open report per module; once ReportHideThreshold
+// users have reported it, the module is hidden until an admin reviews it.
+func (h *Handlers) APIReportModule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	moduleName := strings.TrimSpace(r.FormValue("module"))
+	reason := r.FormValue("reason")
+	details := strings.TrimSpace(r.FormValue("details"))
+	if !validReportReasons[reason] {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false, "error": "Reason must be one of: malicious, broken, spam, other",
+		})
+		return
+	}
+	details = truncate(details, 1000)
+
+	var exists bool
+	if err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM modules WHERE name = ?)", moduleName).Scan(&exists); err != nil {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "Module not found"})
+		return
+	}
+
+	reporter := h.auth.GetUsername(r)
+	var alreadyReported bool
+	err := h.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM module_reports WHERE module_name = ? AND reporter = ? AND status = 'open')
+	`, moduleName, reporter).Scan(&alreadyReported)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+	if alreadyReported {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"success": false, "error": "You have already reported this module",
+		})
+		return
+	}
+
+	if _, err := h.db.Exec(`
+		INSERT INTO module_reports (module_name, reporter, reason, details) VALUES (?, ?, ?, ?)
+	`, moduleName, reporter, reason, details); err != nil {
+		log.Printf("Failed to save report: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to save report"})
+		return
+	}
+	log.Printf("Module %s reported as %s by %s", moduleName, reason, reporter)
+	h.notify(notify.Event{
+		Type:    notify.ModuleReported,
+		Subject: fmt.Sprintf("Your module %s was reported (%s)", moduleName, reason),
+		Body: fmt.Sprintf("A user reported your module %s as %s.\n\nDetails: %s\n\nAn admin will review the report.",
+			moduleName, reason, details),
+		To:   h.moduleOwnerEmails(moduleName),
+		Data: map[string]string{"module": moduleName, "reason": reason},
+	})
+
+	if threshold := h.config.ReportHideThreshold; threshold > 0 {
+		var reporters int
+		err := h.db.QueryRow(`
+			SELECT COUNT(DISTINCT reporter) FROM module_reports WHERE module_name = ? AND status = 'open'
+		`, moduleName).Scan(&reporters)
+		if err != nil {
+			log.Printf("Failed to count reports: %v", err)
+		} else if reporters >= threshold {
+			if _, err := h.db.Exec("UPDATE modules SET hidden = 1 WHERE name = ?", moduleName); err != nil {
+				log.Printf("Failed to hide module %s: %v", moduleName, err)
+			} else {
+				log.Printf("Module %s hidden pending review (%d reports)", moduleName, reporters)
+				h.notify(notify.Event{
+					Type:    notify.ModuleHidden,
+					Subject: fmt.Sprintf("Module %s hidden pending review", moduleName),
+					Body: fmt.Sprintf("%s was reported by %d users and is hidden until an admin reviews it at /admin/reports.",
+						moduleName, reporters),
+					To:   h.moduleOwnerEmails(moduleName),
+					Data: map[string]string{"module": moduleName},
+				})
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Thanks, an admin will review this module",
+	})
+}
+
+// AdminReportsPage shows the queue of modules with open reports
+func (h *Handlers) AdminReportsPage(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.IsAdmin(r) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT rep.id, rep.module_name, rep.reporter, rep.reason, COALESCE(rep.details, ''), rep.created_at,
+		       COALESCE((SELECT MAX(hidden) FROM modules WHERE name = rep.module_name), 0)
+		FROM module_reports rep
+		WHERE rep.status = 'open'
+		ORDER BY rep.module_name, rep.created_at DESC
+	`)
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		http.Error(w, "Failed to load reports", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var modules []*ReportedModule
+	byName := map[string]*ReportedModule{}
+	for rows.Next() {
+		var rep ModuleReport
+		var name string
+		var hidden bool
+		if err := rows.Scan(&rep.ID, &name, &rep.Reporter, &rep.Reason, &rep.Details, &rep.CreatedAt, &hidden); err != nil {
+			log.Printf("Error scanning report: %v", err)
+			continue
+		}
+		m, ok := byName[name]
+		if !ok {
+			m = &ReportedModule{Name: name, Hidden: hidden}
+			byName[name] = m
+			modules = append(modules, m)
+		}
+		m.Reports = append(m.Reports, rep)
+		if rep.CreatedAt.After(m.LastSeen) {
+			m.LastSeen = rep.CreatedAt
+		}
+	}
+
+	data := map[string]interface{}{
+		"Title":     "Module Reports",
+		"LoggedIn":  true,
+		"Session":   h.auth.GetSession(r),
+		"Modules":   modules,
+		"Threshold": h.config.ReportHideThreshold,
+	}
+	if msg := r.URL.Query().Get("success"); msg != "" {
+		data["Success"] = msg
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "reports-admin.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// ResolveReports handles POST /admin/reports/resolve (form: module, action).
+// "dismiss" closes the reports and restores the module; "hide" closes them
+// and keeps the module hidden.
+func (h *Handlers) ResolveReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.auth.IsAdmin(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	moduleName := r.FormValue("module")
+	var status string
+	var hidden bool
+	switch r.FormValue("action") {
+	case "dismiss":
+		status, hidden = "dismissed", false
+	case "hide":
+		status, hidden = "actioned", true
+	default:
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		http.Error(w, "Failed to resolve reports", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+		UPDATE module_reports SET status = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE module_name = ? AND status = 'open'
+	`, status, h.auth.GetUsername(r), moduleName); err != nil {
+		log.Printf("Failed to resolve reports: %v", err)
+		http.Error(w, "Failed to resolve reports", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("UPDATE modules SET hidden = ? WHERE name = ?", hidden, moduleName); err != nil {
+		log.Printf("Failed to update module visibility: %v", err)
+		http.Error(w, "Failed to resolve reports", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to commit: %v", err)
+		http.Error(w, "Failed to resolve reports", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Reports for %s resolved as %s by %s", moduleName, status, h.auth.GetUsername(r))
+	decision := "The reports were dismissed and the module is visible again."
+	if hidden {
+		decision = "The module will stay hidden from the registry."
+	}
+	h.notify(notify.Event{
+		Type:    notify.ModuleModerated,
+		Subject: fmt.Sprintf("Moderation decision for %s", moduleName),
+		Body:    fmt.Sprintf("An admin reviewed the reports against %s. %s", moduleName, decision),
+		To:      h.moduleOwnerEmails(moduleName),
+		Data:    map[string]string{"module": moduleName, "decision": status},
+	})
+	http.Redirect(w, r, "/admin/reports?success="+url.QueryEscape(fmt.Sprintf("Reports for %s %s", moduleName, status)), http.StatusSeeOther)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/themobileprof/clipilot/server/auth"
+	"github.com/themobileprof/clipilot/server/notify"
+)
+
+func TestReportAndResolve(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO modules (name, version, uploaded_by, file_path) VALUES ('demo&x', '1.0.0', 'bob', '/dev/null')`); err != nil {
+		t.Fatal(err)
+	}
+	am := auth.NewManager("admin", "secret")
+	h := &Handlers{db: db, auth: am, notifier: notify.Nop{}}
+	post := func(handler http.HandlerFunc, user string, admin bool, form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, am.WithAPIUser(r, user, admin))
+		return w
+	}
+
+	details := strings.Repeat("é", 1200)
+	w := post(h.APIReportModule, "eve", false, url.Values{"module": {"demo&x"}, "reason": {"spam"}, "details": {details}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("report = %d, want 201", w.Code)
+	}
+	var stored string
+	if err := db.QueryRow("SELECT details FROM module_reports").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(stored) || utf8.RuneCountInString(stored) != 1000 {
+		t.Fatalf("stored details are %d runes (valid UTF-8: %v), want 1000", utf8.RuneCountInString(stored), utf8.ValidString(stored))
+	}
+
+	w = post(h.ResolveReports, "admin", true, url.Values{"module": {"demo&x"}, "action": {"dismiss"}})
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loc.Query().Get("success"); got != "Reports for demo&x dismissed" {
+		t.Fatalf("success message = %q, want the full module name", got)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/themobileprof/clipilot/server/notify"
 )
 
 // ModuleRequest represents a user request for a missing module
@@ -49,15 +51,20 @@ func (h *Handlers) APIModuleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get client info
+	// Get client info; requests from Clio are anonymous, web users are
+	// remembered so they can be told when the request is fulfilled
 	ipAddress := getClientIP(r)
 	userAgent := r.UserAgent()
+	var requestedBy sql.NullString
+	if username := h.auth.GetUsername(r); username != "" {
+		requestedBy = sql.NullString{String: username, Valid: true}
+	}
 
 	// Insert request into database
 	result, err := h.db.Exec(`
-		INSERT INTO module_requests (query, user_context, ip_address, user_agent)
-		VALUES (?, ?, ?, ?)
-	`, query, req.UserContext, ipAddress, userAgent)
+		INSERT INTO module_requests (query, user_context, ip_address, user_agent, requested_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, query, req.UserContext, ipAddress, userAgent, requestedBy)
 
 	if err != nil {
 		log.Printf("Failed to insert module request: %v", err)
@@ -255,6 +262,22 @@ func (h *Handlers) APIUpdateModuleRequest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if update.Status != nil && *update.Status == "completed" {
+		var reqQuery, module, requester string
+		if err := h.db.QueryRow(`
+			SELECT query, COALESCE(fulfilled_by_module, ''), COALESCE(requested_by, '')
+			FROM module_requests WHERE id = ?
+		`, requestID).Scan(&reqQuery, &module, &requester); err == nil {
+			h.notify(notify.Event{
+				Type:    notify.RequestFulfilled,
+				Subject: fmt.Sprintf("Module request #%d fulfilled", requestID),
+				Body:    fmt.Sprintf("The request %q has been fulfilled by module %s.", reqQuery, module),
+				To:      h.userEmails(requester),
+				Data:    map[string]string{"request_id": fmt.Sprint(requestID), "query": reqQuery, "module": module},
+			})
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
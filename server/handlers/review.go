@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/themobileprof/clipilot/server/notify"
+)
+
+// errNotPending is returned when a review decision targets a module that is
+// not waiting for review
+var errNotPending = errors.New("no pending module with that ID")
+
+// notifyModuleSubmitted tells admins (via the admin copy and webhook) that an
+// upload is waiting in the review queue
+func (h *Handlers) notifyModuleSubmitted(name, version, uploader string) {
+	h.notify(notify.Event{
+		Type:    notify.ModuleSubmitted,
+		Subject: fmt.Sprintf("Module %s v%s awaits review", name, version),
+		Body:    fmt.Sprintf("%s uploaded %s v%s. Approve or reject it at /admin/review.", uploader, name, version),
+		Data:    map[string]string{"module": name, "version": version, "uploaded_by": uploader},
+	})
+}
+
+// pendingModules returns the review queue, oldest upload first
+func (h *Handlers) pendingModules() ([]ModuleRecord, error) {
+	rows, err := h.db.Query(`
+		SELECT id, name, version, COALESCE(description, ''), COALESCE(author, ''), uploaded_at, uploaded_by, COALESCE(risk_level, 'low')
+		FROM modules
+		WHERE status = 'pending'
+		ORDER BY uploaded_at ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var modules []ModuleRecord
+	for rows.Next() {
+		m := ModuleRecord{Status: "pending"}
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.Description, &m.Author, &m.UploadedAt, &m.UploadedBy, &m.RiskLevel); err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+	return modules, rows.Err()
+}
+
+// reviewStatus maps a review action to the module status it sets.
+// Rejections need notes so the author knows what to fix.
+func reviewStatus(action, notes string) (string, error) {
+	switch action {
+	case "approve":
+		return "approved", nil
+	case "reject":
+		if notes == "" {
+			return "", fmt.Errorf("notes are required when rejecting a module")
+		}
+		return "rejected", nil
+	}
+	return "", fmt.Errorf("action must be approve or reject")
+}
+
+// reviewModule records an admin's decision on a pending module and notifies
+// its owners. uploaded_at is left alone, so approving an older upload never
+// makes it the latest version; delta sync sees the approval through the
+// module_changes log.
+func (h *Handlers) reviewModule(id int64, status, notes, reviewer string) (string, string, error) {
+	notes = truncate(notes, 2000)
+
+	var name, version string
+	err := h.db.QueryRow("SELECT name, version FROM modules WHERE id = ? AND status = 'pending'", id).Scan(&name, &version)
+	if err == sql.ErrNoRows {
+		return "", "", errNotPending
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	result, err := h.db.Exec(`
+		UPDATE modules SET status = ?, review_notes = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'
+	`, status, notes, reviewer, id)
+	if err != nil {
+		return "", "", err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", "", errNotPending
+	}
+
+	log.Printf("Module %s v%s %s by %s", name, version, status, reviewer)
+	body := fmt.Sprintf("An admin reviewed %s v%s and it is now listed in the registry.", name, version)
+	if status == "rejected" {
+		body = fmt.Sprintf("An admin reviewed %s v%s and rejected it.", name, version)
+	}
+	if notes != "" {
+		body += "\n\nReviewer notes: " + notes
+	}
+	h.notify(notify.Event{
+		Type:    notify.ModuleReviewed,
+		Subject: fmt.Sprintf("Your module %s v%s was %s", name, version, status),
+		Body:    body,
+		To:      h.moduleOwnerEmails(name),
+		Data:    map[string]string{"module": name, "version": version, "decision": status},
+	})
+	return name, version, nil
+}
+
+// AdminReviewPage shows uploads waiting for approval
+func (h *Handlers) AdminReviewPage(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.IsAdmin(r) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	modules, err := h.pendingModules()
+	if err != nil {
+		log.Printf("Error fetching review queue: %v", err)
+		http.Error(w, "Failed to load review queue", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":         "Review Queue",
+		"LoggedIn":      true,
+		"Session":       h.auth.GetSession(r),
+		"Modules":       modules,
+		"ReviewUploads": h.config.ReviewUploads,
+	}
+	if msg := r.URL.Query().Get("success"); msg != "" {
+		data["Success"] = msg
+	}
+	if msg := r.URL.Query().Get("error"); msg != "" {
+		data["Error"] = msg
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "review-admin.html", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// ReviewDecision handles POST /admin/review/decide (form: id, action, notes)
+// from the review page. action is approve or reject; rejecting needs notes.
+func (h *Handlers) ReviewDecision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.auth.IsAdmin(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid module ID", http.StatusBadRequest)
+		return
+	}
+
+	notes := strings.TrimSpace(r.FormValue("notes"))
+	status, err := reviewStatus(r.FormValue("action"), notes)
+	if err != nil {
+		http.Redirect(w, r, "/admin/review?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	name, version, err := h.reviewModule(id, status, notes, h.auth.GetUsername(r))
+	if err == errNotPending {
+		http.Redirect(w, r, "/admin/review?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to review module %d: %v", id, err)
+		http.Error(w, "Failed to record decision", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/review?success="+url.QueryEscape(fmt.Sprintf("%s v%s %s", name, version, status)), http.StatusSeeOther)
+}
+
+// APIReview handles /api/review for admins. GET lists pending modules; POST
+// takes JSON {"id", "action": "approve"|"reject", "notes"} and records the decision.
+func (h *Handlers) APIReview(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.IsAdmin(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "Admin access required"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		modules, err := h.pendingModules()
+		if err != nil {
+			log.Printf("Error fetching review queue: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+			return
+		}
+		pending := []map[string]interface{}{}
+		for _, m := range modules {
+			pending = append(pending, map[string]interface{}{
+				"id":          m.ID,
+				"name":        m.Name,
+				"version":     m.Version,
+				"description": m.Description,
+				"author":      m.Author,
+				"uploaded_by": m.UploadedBy,
+				"uploaded_at": m.UploadedAt,
+				"risk_level":  m.RiskLevel,
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"pending": pending})
+	case http.MethodPost:
+		var req struct {
+			ID     int64  `json:"id"`
+			Action string `json:"action"`
+			Notes  string `json:"notes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Invalid request body"})
+			return
+		}
+		notes := strings.TrimSpace(req.Notes)
+		status, err := reviewStatus(req.Action, notes)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": err.Error()})
+			return
+		}
+		name, version, err := h.reviewModule(req.ID, status, notes, h.auth.GetUsername(r))
+		if err == errNotPending {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to review module %d: %v", req.ID, err)
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"status":  status,
+			"message": fmt.Sprintf("%s v%s %s", name, version, status),
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
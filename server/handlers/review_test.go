@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/themobileprof/clipilot/server/auth"
+	"github.com/themobileprof/clipilot/server/migrations"
+	"github.com/themobileprof/clipilot/server/notify"
+	_ "modernc.org/sqlite"
+)
+
+func TestReviewQueueGatesPublicListing(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	schema, err := migrations.GetInitialSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	result, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, status)
+		VALUES ('pending_mod', '1.0.0', 'bob', '/dev/null', 'pending')
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+	h := &Handlers{db: db, auth: auth.NewManager("admin", "secret"), notifier: notify.Nop{}}
+
+	listed := func() int {
+		w := httptest.NewRecorder()
+		h.APIListModules(w, httptest.NewRequest(http.MethodGet, "/api/modules", nil))
+		var modules []map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&modules); err != nil {
+			t.Fatal(err)
+		}
+		return len(modules)
+	}
+	if n := listed(); n != 0 {
+		t.Fatalf("pending module listed publicly (%d modules)", n)
+	}
+
+	if _, err := reviewStatus("reject", ""); err == nil {
+		t.Fatal("rejection without notes was accepted")
+	}
+	if _, _, err := h.reviewModule(id, "approved", strings.Repeat("é", 2500), "admin"); err != nil {
+		t.Fatal(err)
+	}
+	var notes string
+	if err := db.QueryRow("SELECT review_notes FROM modules WHERE id = ?", id).Scan(&notes); err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(notes) || utf8.RuneCountInString(notes) != 2000 {
+		t.Fatalf("review notes are %d runes (valid UTF-8: %v), want 2000", utf8.RuneCountInString(notes), utf8.ValidString(notes))
+	}
+	if n := listed(); n != 1 {
+		t.Fatalf("approved module not listed (%d modules)", n)
+	}
+	if _, _, err := h.reviewModule(id, "rejected", "too late", "admin"); err != errNotPending {
+		t.Fatalf("second decision err = %v, want errNotPending", err)
+	}
+}
+
+func TestApprovingOlderUploadKeepsLatest(t *testing.T) {
+	db := openTestDB(t)
+	result, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, status, uploaded_at)
+		VALUES ('demo', '1.1.0', 'bob', '/dev/null', 'pending', '2026-01-01 10:00:00')
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingID, _ := result.LastInsertId()
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, status, uploaded_at)
+		VALUES ('demo', '1.2.0', 'admin', '/dev/null', 'approved', '2026-01-02 10:00:00')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handlers{db: db, auth: auth.NewManager("admin", "secret"), notifier: notify.Nop{}}
+
+	if _, _, err := h.reviewModule(pendingID, "approved", "", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	var latest string
+	if err := db.QueryRow(`
+		SELECT version FROM modules WHERE name = 'demo' AND hidden = 0 AND status = 'approved'
+		ORDER BY uploaded_at DESC LIMIT 1
+	`).Scan(&latest); err != nil {
+		t.Fatal(err)
+	}
+	if latest != "1.2.0" {
+		t.Fatalf("latest after approving 1.1.0 = %s, want 1.2.0", latest)
+	}
+
+	var changes int
+	if err := db.QueryRow("SELECT COUNT(*) FROM module_changes WHERE module_name = 'demo'").Scan(&changes); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 2 {
+		t.Fatalf("approval logged %d changes in total, want 2 (upload of 1.2.0 and approval)", changes)
+	}
+}
+
+func TestOverwriteRequiresOwnerOrAdmin(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, uploaded_by, file_path, status)
+		VALUES ('dev_server', '1.0.0', 'bob', '/nonexistent/dev_server.yaml', 'approved')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	am := auth.NewManager("admin", "secret")
+	h := &Handlers{
+		db:       db,
+		auth:     am,
+		notifier: notify.Nop{},
+		config:   Config{UploadsDir: t.TempDir(), ReviewUploads: true},
+	}
+	publishAs := func(user string, admin bool) int {
+		t.Helper()
+		r := am.WithAPIUser(httptest.NewRequest(http.MethodPost, "/api/upload", nil), user, admin)
+		status, _, _, _ := h.publishModule(r, []byte(validModuleYAML), "dev_server.yaml", true)
+		return status
+	}
+	owner := func() (string, string) {
+		t.Helper()
+		var uploadedBy, status string
+		if err := db.QueryRow("SELECT uploaded_by, status FROM modules WHERE name = 'dev_server'").Scan(&uploadedBy, &status); err != nil {
+			t.Fatal(err)
+		}
+		return uploadedBy, status
+	}
+
+	if code := publishAs("eve", false); code != http.StatusForbidden {
+		t.Fatalf("overwrite by another user = %d, want 403", code)
+	}
+	if by, status := owner(); by != "bob" || status != "approved" {
+		t.Fatalf("after rejected overwrite: %s/%s, want bob/approved", by, status)
+	}
+	if code := publishAs("bob", false); code != http.StatusOK {
+		t.Fatalf("overwrite by the owner = %d, want 200", code)
+	}
+	if by, status := owner(); by != "bob" || status != "pending" {
+		t.Fatalf("after owner overwrite: %s/%s, want bob/pending", by, status)
+	}
+	if code := publishAs("admin", true); code != http.StatusOK {
+		t.Fatalf("overwrite by an admin = %d, want 200", code)
+	}
+}
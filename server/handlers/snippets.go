@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/themobileprof/clipilot/server/scanner"
+)
+
+var (
+	snippetNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	placeholderRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// maxSnippetCommand keeps snippets to one-liners; longer scripts belong in modules
+const maxSnippetCommand = 1000
+
+// Snippet is a short parameterized command shared through the registry
+type Snippet struct {
+	Name         string   `json:"name"`
+	Command      string   `json:"command"`
+	Description  string   `json:"description"`
+	Tags         []string `json:"tags"`
+	Placeholders []string `json:"placeholders"`
+	RiskLevel    string   `json:"risk_level"`
+	UploadedBy   string   `json:"uploaded_by"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+// snippetPlaceholders returns the {{name}} placeholders in command, in order of first use
+func snippetPlaceholders(command string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, m := range placeholderRegex.FindAllStringSubmatch(command, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+func validateSnippet(s *Snippet) error {
+	if !snippetNameRegex.MatchString(s.Name) {
+		return fmt.Errorf("name must be lowercase alphanumeric with underscores only")
+	}
+	if s.Command == "" {
+		return fmt.Errorf("command is required")
+	}
+	if len(s.Command) > maxSnippetCommand {
+		return fmt.Errorf("command too long (max %d characters)", maxSnippetCommand)
+	}
+	if s.Description == "" {
+		return fmt.Errorf("description is required")
+	}
+	if len(s.Description) > 500 {
+		return fmt.Errorf("description too long (max 500 characters)")
+	}
+	if len(s.Tags) > 20 {
+		return fmt.Errorf("too many tags (max 20)")
+	}
+	for i, tag := range s.Tags {
+		if len(tag) > 50 {
+			return fmt.Errorf("tag %d too long (max 50 characters)", i)
+		}
+	}
+	return nil
+}
+
+// APISnippets handles GET /api/v1/snippets?search=&tags=&limit=&offset= and
+// POST /api/v1/snippets (logged in; JSON body with name, command, description, tags).
+// Posting an existing name updates it if the caller uploaded it.
+func (h *Handlers) APISnippets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listSnippets(w, r)
+	case http.MethodPost:
+		h.RequireAuth(h.saveSnippet)(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handlers) saveSnippet(w http.ResponseWriter, r *http.Request) {
+	var s Snippet
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&s); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Invalid request body"})
+		return
+	}
+	s.Name = strings.TrimSpace(s.Name)
+	s.Command = strings.TrimSpace(s.Command)
+	s.Description = strings.TrimSpace(s.Description)
+	if err := validateSnippet(&s); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": err.Error()})
+		return
+	}
+	if index, err := loadCategoryIndex(h.db); err != nil {
+		log.Printf("Warning: failed to load categories: %v", err)
+		s.Tags = canonicalizeTags(nil, s.Tags)
+	} else {
+		s.Tags = canonicalizeTags(index, s.Tags)
+	}
+	s.Placeholders = snippetPlaceholders(s.Command)
+
+	// Snippets skip module review, so anything the scanner rates high is refused
+	s.RiskLevel = scanner.RiskLow
+	for _, f := range scanner.ScanCommand(s.Command) {
+		if s.RiskLevel != scanner.RiskHigh {
+			s.RiskLevel = f.Severity
+		}
+	}
+	if s.RiskLevel == scanner.RiskHigh {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false, "error": "Command matches a high-risk pattern; publish it as a module so it can be reviewed",
+		})
+		return
+	}
+
+	user := h.auth.GetUsername(r)
+	var owner string
+	err := h.db.QueryRow("SELECT uploaded_by FROM snippets WHERE name = ?", s.Name).Scan(&owner)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Database error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Internal server error"})
+		return
+	}
+	if err == nil && owner != user {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"success": false, "error": fmt.Sprintf("Snippet '%s' belongs to another user", s.Name),
+		})
+		return
+	}
+
+	tagsJSON, _ := json.Marshal(s.Tags)
+	placeholdersJSON, _ := json.Marshal(s.Placeholders)
+	if _, err := h.db.Exec(`
+		INSERT INTO snippets (name, command, description, tags, placeholders, risk_level, uploaded_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			command = excluded.command,
+			description = excluded.description,
+			tags = excluded.tags,
+			placeholders = excluded.placeholders,
+			risk_level = excluded.risk_level,
+			updated_at = CURRENT_TIMESTAMP
+	`, s.Name, s.Command, s.Description, string(tagsJSON), string(placeholdersJSON), s.RiskLevel, user); err != nil {
+		log.Printf("Failed to save snippet %s: %v", s.Name, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "Failed to save snippet"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":      true,
+		"name":         s.Name,
+		"placeholders": s.Placeholders,
+		"risk_level":   s.RiskLevel,
+	})
+}
+
+func (h *Handlers) listSnippets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	sqlQuery := "SELECT name, command, description, tags, placeholders, risk_level, uploaded_by, updated_at FROM snippets WHERE 1 = 1"
+	args := []interface{}{}
+	if search := strings.TrimSpace(query.Get("search")); search != "" {
+		sqlQuery += " AND (name LIKE '%' || ? || '%' OR description LIKE '%' || ? || '%' OR command LIKE '%' || ? || '%')"
+		args = append(args, search, search, search)
+	}
+	if tags := query.Get("tags"); tags != "" {
+		var conditions []string
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				conditions = append(conditions, "tags LIKE ?")
+				args = append(args, `%"`+tag+`"%`)
+			}
+		}
+		if len(conditions) > 0 {
+			sqlQuery += " AND (" + strings.Join(conditions, " OR ") + ")"
+		}
+	}
+
+	var total int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM ("+sqlQuery+")", args...).Scan(&total); err != nil {
+		log.Printf("Count query error: %v", err)
+	}
+
+	rows, err := h.db.Query(sqlQuery+" ORDER BY name LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	snippets := []Snippet{}
+	for rows.Next() {
+		var s Snippet
+		var tagsJSON, placeholdersJSON string
+		var updatedAt time.Time
+		if err := rows.Scan(&s.Name, &s.Command, &s.Description, &tagsJSON, &placeholdersJSON, &s.RiskLevel, &s.UploadedBy, &updatedAt); err != nil {
+			log.Printf("Scan error: %v", err)
+			continue
+		}
+		_ = json.Unmarshal([]byte(tagsJSON), &s.Tags)
+		_ = json.Unmarshal([]byte(placeholdersJSON), &s.Placeholders)
+		s.UpdatedAt = updatedAt.Format(time.RFC3339)
+		snippets = append(snippets, s)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"snippets": snippets,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
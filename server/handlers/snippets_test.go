@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnippetPlaceholders(t *testing.T) {
+	got := snippetPlaceholders("rsync -az {{src}} {{ host }}:{{dest}} && echo {{src}} {{.state}}")
+	want := []string{"src", "host", "dest"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("snippetPlaceholders = %v, want %v", got, want)
+	}
+}
+
+func TestValidateSnippet(t *testing.T) {
+	s := &Snippet{Name: "rsync_push", Command: "rsync -az {{src}} {{dest}}", Description: "Push a folder"}
+	if err := validateSnippet(s); err != nil {
+		t.Fatalf("valid snippet rejected: %v", err)
+	}
+	s.Name = "Rsync-Push"
+	if err := validateSnippet(s); err == nil {
+		t.Fatal("invalid name accepted")
+	}
+	s.Name, s.Command = "rsync_push", strings.Repeat("x", maxSnippetCommand+1)
+	if err := validateSnippet(s); err == nil {
+		t.Fatal("oversized command accepted")
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+var (
+	stateKeyRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	envNameRegex  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// validProcessActions are the checks a "process" step can run on a background step
+var validProcessActions = map[string]bool{
+	"status": true,
+	"stop":   true,
+}
+
+// validOfflineModes are what the runner may do when a network step finds no connectivity
+var validOfflineModes = map[string]bool{
+	"skip": true,
+	"wait": true,
+}
+
+// validFailureModes are the non-interactive defaults of the recovery menu
+var validFailureModes = map[string]bool{
+	"retry": true,
+	"skip":  true,
+	"abort": true,
+}
+
+// maxStepRetries and maxRetryDelay bound retries so a broken step cannot
+// stall a flow for ever
+const (
+	maxStepRetries = 10
+	maxRetryDelay  = 10 * time.Minute
+)
+
+// maxTimeoutSeconds caps module and step command timeouts at one day
+const maxTimeoutSeconds = 24 * 60 * 60
+
+// validateStepOptions checks the optional execution settings of each step
+// (background processes, network requirements, cwd/env, failure recovery,
+// prompts, ...)
+// that Clio's runner honours
+func validateStepOptions(flowName string, flow *models.Flow) error {
+	// PID keys recorded by background steps in this flow
+	pidKeys := map[string]bool{}
+	for stepKey, step := range flow.Steps {
+		if step.Background {
+			pidKeys[backgroundPIDKey(stepKey, step)] = true
+		}
+	}
+
+	for stepKey, step := range flow.Steps {
+		if step.Background && step.Type != "action" {
+			return fmt.Errorf("flow '%s', step '%s': background is only allowed on action steps", flowName, stepKey)
+		}
+		if step.PIDKey != "" && !stateKeyRegex.MatchString(step.PIDKey) {
+			return fmt.Errorf("flow '%s', step '%s': pid_key must be lowercase alphanumeric with underscores", flowName, stepKey)
+		}
+		if step.PIDKey != "" && !step.Background && step.Type != "process" {
+			return fmt.Errorf("flow '%s', step '%s': pid_key requires background: true or type: process", flowName, stepKey)
+		}
+
+		if step.OnOffline != "" {
+			if !step.RequiresNetwork {
+				return fmt.Errorf("flow '%s', step '%s': on_offline requires requires_network: true", flowName, stepKey)
+			}
+			if !validOfflineModes[step.OnOffline] {
+				return fmt.Errorf("flow '%s', step '%s': on_offline must be 'skip' or 'wait'", flowName, stepKey)
+			}
+		}
+
+		if step.OnFailure != "" && !validFailureModes[step.OnFailure] {
+			return fmt.Errorf("flow '%s', step '%s': on_failure must be 'retry', 'skip' or 'abort'", flowName, stepKey)
+		}
+		if step.MaxRetries != 0 && step.Retries != 0 {
+			return fmt.Errorf("flow '%s', step '%s': set either retries or max_retries, not both", flowName, stepKey)
+		}
+		retries := step.MaxRetries + step.Retries
+		if retries != 0 && step.OnFailure != "retry" {
+			return fmt.Errorf("flow '%s', step '%s': max_retries requires on_failure: retry", flowName, stepKey)
+		}
+		if retries < 0 || retries > maxStepRetries {
+			return fmt.Errorf("flow '%s', step '%s': max_retries must be between 1 and %d, or omitted", flowName, stepKey, maxStepRetries)
+		}
+		if step.RetryDelay != "" {
+			if step.OnFailure != "retry" {
+				return fmt.Errorf("flow '%s', step '%s': retry_delay requires on_failure: retry", flowName, stepKey)
+			}
+			d, err := time.ParseDuration(step.RetryDelay)
+			if err != nil || d <= 0 || d > maxRetryDelay {
+				return fmt.Errorf("flow '%s', step '%s': retry_delay must be a positive duration up to %s (e.g. 5s)", flowName, stepKey, maxRetryDelay)
+			}
+		}
+		if step.OnError != "" {
+			if step.OnError == stepKey {
+				return fmt.Errorf("flow '%s', step '%s': on_error cannot point at the step itself", flowName, stepKey)
+			}
+			if _, ok := flow.Steps[step.OnError]; !ok {
+				return fmt.Errorf("flow '%s', step '%s': on_error step '%s' does not exist in this flow", flowName, stepKey, step.OnError)
+			}
+		}
+
+		if step.Rollback != "" && step.Type != "action" {
+			return fmt.Errorf("flow '%s', step '%s': rollback is only allowed on action steps", flowName, stepKey)
+		}
+		if err := checkTemplate(step.Rollback); err != nil {
+			return fmt.Errorf("flow '%s', step '%s': invalid rollback template: %v", flowName, stepKey, err)
+		}
+		if step.SkipIf != "" && step.Type != "action" {
+			return fmt.Errorf("flow '%s', step '%s': skip_if is only allowed on action steps", flowName, stepKey)
+		}
+		if err := checkTemplate(step.SkipIf); err != nil {
+			return fmt.Errorf("flow '%s', step '%s': invalid skip_if template: %v", flowName, stepKey, err)
+		}
+
+		for i, v := range step.Validate {
+			if v.CheckCommand == "" {
+				if v.WarnOnly {
+					return fmt.Errorf("flow '%s', step '%s': validate[%d]: warn_only needs a check_command to warn about", flowName, stepKey, i)
+				}
+				return fmt.Errorf("flow '%s', step '%s': validate[%d]: check_command is required", flowName, stepKey, i)
+			}
+			if err := checkTemplate(v.CheckCommand); err != nil {
+				return fmt.Errorf("flow '%s', step '%s': validate[%d]: invalid check_command template: %v", flowName, stepKey, i, err)
+			}
+		}
+
+		if step.TimeoutSeconds != 0 {
+			if step.Command == "" {
+				return fmt.Errorf("flow '%s', step '%s': timeout_seconds needs a command to apply to", flowName, stepKey)
+			}
+			if step.TimeoutSeconds < 0 || step.TimeoutSeconds > maxTimeoutSeconds {
+				return fmt.Errorf("flow '%s', step '%s': timeout_seconds must be between 1 and %d, or omitted", flowName, stepKey, maxTimeoutSeconds)
+			}
+		}
+
+		if (step.Cwd != "" || len(step.Env) > 0) && step.Command == "" {
+			return fmt.Errorf("flow '%s', step '%s': cwd and env need a command to apply to", flowName, stepKey)
+		}
+		if err := checkTemplate(step.Cwd); err != nil {
+			return fmt.Errorf("flow '%s', step '%s': invalid cwd template: %v", flowName, stepKey, err)
+		}
+		if err := validateEnv(step.Env); err != nil {
+			return fmt.Errorf("flow '%s', step '%s': %v", flowName, stepKey, err)
+		}
+
+		if step.Type == "prompt" {
+			if step.Var == "" {
+				return fmt.Errorf("flow '%s', step '%s': var is required for prompt steps", flowName, stepKey)
+			}
+			if !stateKeyRegex.MatchString(step.Var) {
+				return fmt.Errorf("flow '%s', step '%s': var must be lowercase alphanumeric with underscores", flowName, stepKey)
+			}
+			if step.Pattern != "" {
+				re, err := regexp.Compile(`^(?:` + step.Pattern + `)$`)
+				if err != nil {
+					return fmt.Errorf("flow '%s', step '%s': invalid pattern: %v", flowName, stepKey, err)
+				}
+				if step.Default != "" && !re.MatchString(step.Default) {
+					return fmt.Errorf("flow '%s', step '%s': default does not match pattern", flowName, stepKey)
+				}
+			}
+		} else if step.Var != "" || step.Pattern != "" || step.Default != "" || step.Secret {
+			return fmt.Errorf("flow '%s', step '%s': var, pattern, default and secret are only allowed on prompt steps", flowName, stepKey)
+		}
+
+		if step.Type == "process" {
+			if !validProcessActions[step.Process] {
+				return fmt.Errorf("flow '%s', step '%s': process must be 'status' or 'stop'", flowName, stepKey)
+			}
+			if step.PIDKey == "" {
+				return fmt.Errorf("flow '%s', step '%s': pid_key is required for process steps", flowName, stepKey)
+			}
+			if !pidKeys[step.PIDKey] {
+				return fmt.Errorf("flow '%s', step '%s': pid_key '%s' is not set by any background step in this flow", flowName, stepKey, step.PIDKey)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEnv checks variable names and {{.key}} templates of a module or step env map
+func validateEnv(env map[string]string) error {
+	for name, value := range env {
+		if !envNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name '%s'", name)
+		}
+		if err := checkTemplate(value); err != nil {
+			return fmt.Errorf("invalid template in env %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// environmentDistros are the platform names a module environment may list,
+// matching the command catalog's platforms
+var environmentDistros = map[string]bool{
+	"linux": true, "termux": true, "macos": true, "debian": true, "fedora": true, "arch": true,
+}
+
+var (
+	toolNameRegex          = regexp.MustCompile(`^[A-Za-z0-9._+-]+$`)
+	versionConstraintRegex = regexp.MustCompile(`^(>=|<=|>|<|==|=)?\s*\d+(\.\d+)*$`)
+)
+
+// validateEnvironment checks a module's declared environment expectations
+func validateEnvironment(env *models.Environment) error {
+	if env == nil {
+		return nil
+	}
+	for _, distro := range env.Distros {
+		if !environmentDistros[distro] {
+			return fmt.Errorf("environment: unknown distro '%s' (use linux, termux, macos, debian, fedora or arch)", distro)
+		}
+	}
+	for tool, constraint := range env.Tools {
+		if !toolNameRegex.MatchString(tool) {
+			return fmt.Errorf("environment: invalid tool name '%s'", tool)
+		}
+		if strings.TrimSpace(constraint) == "" {
+			continue
+		}
+		for _, part := range strings.Split(constraint, ",") {
+			if !versionConstraintRegex.MatchString(strings.TrimSpace(part)) {
+				return fmt.Errorf("environment: tool %s: invalid version constraint '%s' (e.g. \">=2.30, <3\")", tool, constraint)
+			}
+		}
+	}
+	if env.MinFreeDiskMB < 0 {
+		return fmt.Errorf("environment: min_free_disk_mb cannot be negative")
+	}
+	return nil
+}
+
+// gotoFlowCycle returns a chain of flows that jump back to themselves
+// through goto_flow steps (e.g. main, configure, main), or nil
+func gotoFlowCycle(module *models.Module) []string {
+	edges := map[string][]string{}
+	var names []string
+	for name, flow := range module.Flows {
+		names = append(names, name)
+		if flow == nil {
+			continue
+		}
+		for _, step := range flow.Steps {
+			if step.Type == "goto_flow" && step.Flow != "" {
+				edges[name] = append(edges[name], step.Flow)
+			}
+		}
+		sort.Strings(edges[name])
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, next := range edges[name] {
+			switch state[next] {
+			case visiting:
+				for i, p := range path {
+					if p == next {
+						return append(append([]string{}, path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// backgroundPIDKey is the state key a background step stores its PID under
+func backgroundPIDKey(stepKey string, step *models.Step) string {
+	if step.PIDKey != "" {
+		return step.PIDKey
+	}
+	return stepKey + "_pid"
+}
+
+// checkTemplate makes sure a {{.key}} state template parses
+func checkTemplate(s string) error {
+	_, err := template.New("").Parse(s)
+	return err
+}
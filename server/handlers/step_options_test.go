@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// validModuleYAML passes validateModule: it starts a dev server in the
+// background, checks it and stops it again
+const validModuleYAML = `
+name: dev_server
+version: 1.0.0
+description: Start and stop a dev server
+tags: [server]
+flows:
+  main:
+    start: serve
+    steps:
+      serve:
+        type: action
+        command: python3 -m http.server 8000
+        background: true
+        next: check
+      check:
+        type: process
+        process: status
+        pid_key: serve_pid
+        next: stop
+      stop:
+        type: process
+        process: stop
+        pid_key: serve_pid
+`
+
+// validModule returns a fresh copy of validModuleYAML for a test to modify
+func validModule(t *testing.T) *models.Module {
+	t.Helper()
+	var m models.Module
+	if err := yaml.Unmarshal([]byte(validModuleYAML), &m); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return &m
+}
+
+// mainStep returns a step of the fixture's main flow
+func mainStep(m *models.Module, key string) *models.Step {
+	return m.Flows["main"].Steps[key]
+}
+
+// moduleCase changes a valid module; wantErr is a substring of the expected
+// validation error, or empty when the module must still be accepted
+type moduleCase struct {
+	name    string
+	mutate  func(m *models.Module)
+	wantErr string
+}
+
+func runModuleCases(t *testing.T, cases []moduleCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := validModule(t)
+			tc.mutate(m)
+			err := validateModule(m)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("valid module rejected: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBackgroundSteps(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"fixture", func(m *models.Module) {}, ""},
+		{"unknown pid_key", func(m *models.Module) {
+			mainStep(m, "check").PIDKey = "other_pid"
+		}, "not set by any background step"},
+		{"background on a process step", func(m *models.Module) {
+			mainStep(m, "check").Background = true
+		}, "background is only allowed on action steps"},
+	})
+}
+
+func TestValidateOfflineMode(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"requires_network with on_offline", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.RequiresNetwork, serve.OnOffline = true, "skip"
+		}, ""},
+		{"on_offline without requires_network", func(m *models.Module) {
+			mainStep(m, "serve").OnOffline = "skip"
+		}, "on_offline requires requires_network"},
+		{"unknown on_offline", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.RequiresNetwork, serve.OnOffline = true, "retry"
+		}, "on_offline must be"},
+	})
+}
+
+func TestValidateStepEnv(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"templated cwd and env", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.Cwd = "{{.project_dir}}"
+			serve.Env = map[string]string{"PORT": "{{.port}}"}
+		}, ""},
+		{"invalid env name", func(m *models.Module) {
+			mainStep(m, "serve").Env = map[string]string{"BAD-NAME": "x"}
+		}, "invalid environment variable name"},
+		{"broken env template", func(m *models.Module) {
+			mainStep(m, "serve").Env = map[string]string{"PORT": "{{.port"}
+		}, "invalid template in env PORT"},
+		{"env without command", func(m *models.Module) {
+			mainStep(m, "check").Env = map[string]string{"PORT": "8000"}
+		}, "cwd and env need a command"},
+	})
+}
+
+func TestValidateFailureMode(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"retry with max_retries", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.OnFailure, serve.MaxRetries = "retry", 3
+		}, ""},
+		{"max_retries without retry", func(m *models.Module) {
+			mainStep(m, "serve").MaxRetries = 3
+		}, "max_retries requires on_failure: retry"},
+		{"unknown on_failure", func(m *models.Module) {
+			mainStep(m, "serve").OnFailure = "ignore"
+		}, "on_failure must be"},
+		{"retries alias", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.OnFailure, serve.Retries = "retry", 3
+		}, ""},
+		{"retries alias without retry", func(m *models.Module) {
+			mainStep(m, "serve").Retries = 3
+		}, "max_retries requires on_failure: retry"},
+		{"retries and max_retries", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.OnFailure, serve.Retries, serve.MaxRetries = "retry", 3, 3
+		}, "set either retries or max_retries"},
+		{"too many retries", func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.OnFailure, serve.Retries = "retry", maxStepRetries+1
+		}, "max_retries must be between 1 and 10, or omitted"},
+	})
+}
+
+func TestValidatePromptStep(t *testing.T) {
+	withPrompt := func(edit func(ask *models.Step)) func(m *models.Module) {
+		return func(m *models.Module) {
+			ask := &models.Step{Type: "prompt", Message: "Port?", Var: "port", Pattern: `[0-9]+`, Default: "8000", Next: "serve"}
+			edit(ask)
+			m.Flows["main"].Steps["ask"] = ask
+		}
+	}
+	runModuleCases(t, []moduleCase{
+		{"prompt with pattern and default", withPrompt(func(ask *models.Step) {}), ""},
+		{"default not matching pattern", withPrompt(func(ask *models.Step) {
+			ask.Default = "eighty"
+		}), "default does not match"},
+		{"invalid pattern", withPrompt(func(ask *models.Step) {
+			ask.Default, ask.Pattern = "", "[0-9"
+		}), "invalid pattern"},
+		{"prompt without var", withPrompt(func(ask *models.Step) {
+			ask.Pattern, ask.Var = "", ""
+		}), "var is required"},
+		{"secret on an action step", func(m *models.Module) {
+			mainStep(m, "serve").Secret = true
+		}, "only allowed on prompt steps"},
+	})
+}
+
+func TestValidateRetryDelayAndOnError(t *testing.T) {
+	retrying := func(delay, onError string) func(m *models.Module) {
+		return func(m *models.Module) {
+			serve := mainStep(m, "serve")
+			serve.OnFailure, serve.MaxRetries = "retry", 3
+			serve.RetryDelay, serve.OnError = delay, onError
+		}
+	}
+	runModuleCases(t, []moduleCase{
+		{"retry_delay and on_error", retrying("5s", "stop"), ""},
+		{"retry_delay without unit", retrying("5", "stop"), "retry_delay must be a positive duration"},
+		{"retry_delay over the cap", retrying("1h", "stop"), "retry_delay must be a positive duration"},
+		{"unknown on_error step", retrying("5s", "missing"), "does not exist"},
+		{"on_error pointing at itself", retrying("5s", "serve"), "cannot point at the step itself"},
+	})
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"module and step timeouts", func(m *models.Module) {
+			m.TimeoutSeconds = 600
+			mainStep(m, "serve").TimeoutSeconds = 30
+		}, ""},
+		{"step timeout without command", func(m *models.Module) {
+			mainStep(m, "check").TimeoutSeconds = 30
+		}, "timeout_seconds needs a command"},
+		{"negative module timeout", func(m *models.Module) {
+			m.TimeoutSeconds = -1
+		}, "timeout_seconds must be between 1 and 86400, or omitted"},
+		{"step timeout over a day", func(m *models.Module) {
+			mainStep(m, "serve").TimeoutSeconds = maxTimeoutSeconds + 1
+		}, "timeout_seconds must be between 1 and 86400, or omitted"},
+	})
+}
+
+func TestValidateGotoFlow(t *testing.T) {
+	configure := &models.Flow{Start: "done", Steps: map[string]*models.Step{
+		"done": {Type: "terminal", Message: "Configured"},
+	}}
+	jump := func(m *models.Module) {
+		mainStep(m, "stop").Next = "more"
+		m.Flows["main"].Steps["more"] = &models.Step{Type: "goto_flow", Flow: "configure"}
+	}
+	runModuleCases(t, []moduleCase{
+		{"jump to another flow", func(m *models.Module) {
+			jump(m)
+			m.Flows["configure"] = configure
+		}, ""},
+		{"missing target flow", jump, "does not exist"},
+		{"jump to its own flow", func(m *models.Module) {
+			m.Flows["main"].Steps["more"] = &models.Step{Type: "goto_flow", Flow: "main"}
+		}, "cannot jump to its own flow"},
+		{"flow on a non-goto step", func(m *models.Module) {
+			mainStep(m, "serve").Flow = "configure"
+			m.Flows["configure"] = configure
+		}, "flow is only allowed on goto_flow steps"},
+		{"target without steps", func(m *models.Module) {
+			jump(m)
+			m.Flows["configure"] = &models.Flow{}
+		}, "flow 'configure' has no steps"},
+		{"cycle through two flows", func(m *models.Module) {
+			jump(m)
+			m.Flows["configure"] = &models.Flow{Start: "back", Steps: map[string]*models.Step{
+				"back": {Type: "goto_flow", Flow: "main"},
+			}}
+		}, "cycle: configure -> main -> configure"},
+		{"cycle through three flows", func(m *models.Module) {
+			jump(m)
+			m.Flows["configure"] = &models.Flow{Start: "next", Steps: map[string]*models.Step{
+				"next": {Type: "goto_flow", Flow: "verify"},
+			}}
+			m.Flows["verify"] = &models.Flow{Start: "back", Steps: map[string]*models.Step{
+				"back": {Type: "goto_flow", Flow: "configure"},
+			}}
+		}, "cycle: configure -> verify -> configure"},
+	})
+}
+
+func TestValidateModuleEnv(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"module env", func(m *models.Module) {
+			m.Env = map[string]string{"LANG": "C.UTF-8", "PATH": "{{.home}}/bin:/usr/bin"}
+		}, ""},
+		{"invalid module env name", func(m *models.Module) {
+			m.Env = map[string]string{"1BAD": "x"}
+		}, "invalid environment variable name"},
+	})
+}
+
+func TestValidateEnvironment(t *testing.T) {
+	withEnvironment := func(env models.Environment) func(m *models.Module) {
+		return func(m *models.Module) { m.Environment = &env }
+	}
+	runModuleCases(t, []moduleCase{
+		{"distros, tools and disk", withEnvironment(models.Environment{
+			Distros:       []string{"debian", "termux"},
+			Tools:         map[string]string{"python3": ">=3.8, <4", "git": ""},
+			MinFreeDiskMB: 200,
+		}), ""},
+		{"invalid version constraint", withEnvironment(models.Environment{
+			Tools: map[string]string{"python3": "newest"},
+		}), "invalid version constraint"},
+		{"unknown distro", withEnvironment(models.Environment{
+			Distros: []string{"windows"},
+		}), "unknown distro"},
+		{"negative disk", withEnvironment(models.Environment{
+			MinFreeDiskMB: -1,
+		}), "cannot be negative"},
+	})
+}
+
+func TestValidateChecks(t *testing.T) {
+	withChecks := func(checks ...models.Validation) func(m *models.Module) {
+		return func(m *models.Module) { mainStep(m, "serve").Validate = checks }
+	}
+	tooMany := make([]models.Validation, maxValidationResults+1)
+	for i := range tooMany {
+		tooMany[i] = models.Validation{CheckCommand: "true"}
+	}
+	runModuleCases(t, []moduleCase{
+		{"critical and warn_only checks", withChecks(
+			models.Validation{CheckCommand: "curl -sf localhost:{{.port}}", ErrorMessage: "server not up"},
+			models.Validation{CheckCommand: "test -f ~/.cache/index", WarnOnly: true},
+		), ""},
+		{"warn_only without check_command", withChecks(
+			models.Validation{WarnOnly: true, ErrorMessage: "cache missing"},
+		), "warn_only needs a check_command"},
+		{"check without check_command", withChecks(
+			models.Validation{Expected: "ok"},
+		), "check_command is required"},
+		{"malformed check template", withChecks(
+			models.Validation{CheckCommand: "test -f {{.path", WarnOnly: true},
+		), "invalid check_command template"},
+		{"more checks than a run report holds", withChecks(tooMany...), "too many validate checks"},
+	})
+}
+
+func TestValidateRollback(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"templated rollback on an action step", func(m *models.Module) {
+			mainStep(m, "serve").Rollback = "rm -rf {{.project_dir}}/.cache"
+		}, ""},
+		{"rollback on a process step", func(m *models.Module) {
+			mainStep(m, "check").Rollback = "true"
+		}, "rollback is only allowed on action steps"},
+		{"malformed template", func(m *models.Module) {
+			mainStep(m, "serve").Rollback = "rm -rf {{.dir"
+		}, "invalid rollback template"},
+	})
+}
+
+func TestValidateSkipIf(t *testing.T) {
+	runModuleCases(t, []moduleCase{
+		{"templated check on an action step", func(m *models.Module) {
+			mainStep(m, "serve").SkipIf = "curl -sf http://localhost:{{.port}}/"
+		}, ""},
+		{"skip_if on a process step", func(m *models.Module) {
+			mainStep(m, "check").SkipIf = "true"
+		}, "skip_if is only allowed on action steps"},
+		{"malformed template", func(m *models.Module) {
+			mainStep(m, "serve").SkipIf = "test -f {{.path"
+		}, "invalid skip_if template"},
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/themobileprof/clipilot/server/catalog"
+)
+
+// suggestModulesQuery ranks name-prefix matches above tag matches, then by
+// popularity. It is prepared once in New since it runs on every keystroke.
+const suggestModulesQuery = `
+	SELECT name, COALESCE(MAX(description), '')
+	FROM modules
+	WHERE hidden = 0 AND status = 'approved' AND (name LIKE ? ESCAPE '\' OR tags LIKE ? ESCAPE '\')
+	GROUP BY name
+	ORDER BY MAX(name LIKE ? ESCAPE '\') DESC, SUM(downloads) DESC, name
+	LIMIT ?
+`
+
+// likeEscaper escapes LIKE wildcards in user input for patterns using ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Suggestion is one search-as-you-type result
+type Suggestion struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// APISuggest handles GET /api/suggest?q=&limit=
+// It returns module names and catalog commands matching the typed prefix.
+func (h *Handlers) APISuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := truncate(strings.TrimSpace(r.URL.Query().Get("q")), 64)
+	limit := 8
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 20 {
+		limit = l
+	}
+
+	modules := []Suggestion{}
+	commands := []Suggestion{}
+
+	if q != "" {
+		escaped := likeEscaper.Replace(strings.ToLower(q))
+		rows, err := h.suggestStmt.Query(escaped+"%", `%"`+escaped+"%", escaped+"%", limit)
+		if err != nil {
+			log.Printf("Suggest query error: %v", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s Suggestion
+			if err := rows.Scan(&s.Name, &s.Description); err != nil {
+				log.Printf("Scan error: %v", err)
+				continue
+			}
+			modules = append(modules, s)
+		}
+
+		for _, entry := range catalog.Complete(q, limit) {
+			commands = append(commands, Suggestion{Name: entry.Name, Description: entry.Description})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":    q,
+		"modules":  modules,
+		"commands": commands,
+	}); err != nil {
+		log.Printf("Failed to encode suggest response: %v", err)
+	}
+}
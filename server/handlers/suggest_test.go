@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAPISuggest(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO modules (name, version, description, tags, uploaded_by, file_path, downloads, hidden, status) VALUES
+			('nginx_setup', '1.0.0', 'Install nginx', '["web"]', 'bob', '/dev/null', 5, 0, 'approved'),
+			('nginx_tune', '1.0.0', 'Tune nginx', '["web"]', 'bob', '/dev/null', 9, 0, 'approved'),
+			('webhook_relay', '1.0.0', 'Relay hooks', '["nginx"]', 'bob', '/dev/null', 50, 0, 'approved'),
+			('nginx_hidden', '1.0.0', 'Hidden', '[]', 'bob', '/dev/null', 0, 1, 'approved'),
+			('nginx_pending', '1.0.0', 'Pending', '[]', 'bob', '/dev/null', 0, 0, 'pending'),
+			('n_underscore', '1.0.0', 'Literal underscore', '[]', 'bob', '/dev/null', 0, 0, 'approved')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare(suggestModulesQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	h := &Handlers{db: db, suggestStmt: stmt}
+
+	type response struct {
+		Query   string       `json:"query"`
+		Modules []Suggestion `json:"modules"`
+	}
+	suggest := func(q string) response {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.APISuggest(w, httptest.NewRequest(http.MethodGet, "/api/suggest?q="+url.QueryEscape(q), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET q=%q = %d, want 200", q, w.Code)
+		}
+		var resp response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	names := func(resp response) string {
+		var out []string
+		for _, m := range resp.Modules {
+			out = append(out, m.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{"nginx", "nginx_tune,nginx_setup,webhook_relay"},
+		{"NGINX_S", "nginx_setup"},
+		{"n_", "n_underscore"},
+		{"%", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := names(suggest(tt.q)); got != tt.want {
+			t.Errorf("suggest(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("é", 100)
+	if got := suggest(long).Query; !utf8.ValidString(got) || utf8.RuneCountInString(got) != 64 {
+		t.Errorf("long query echoed as %q, want 64 whole runes", got)
+	}
+}
@@ -14,6 +14,15 @@ CREATE TABLE IF NOT EXISTS modules (
     file_path TEXT NOT NULL,
     original_filename TEXT,
     downloads INTEGER DEFAULT 0,
+    risk_level TEXT DEFAULT 'low', -- Upload-time command scan result: low, medium, high
+    risk_report TEXT, -- JSON scanner report: level and findings (flow, step, rule, severity, message, command)
+    hidden BOOLEAN DEFAULT 0, -- Set when abuse reports reach the threshold, pending admin review
+    provides TEXT DEFAULT '[]', -- JSON array of capabilities other modules can require
+    checksum TEXT, -- SHA-256 (hex) of the uploaded YAML, recorded at upload time
+    status TEXT DEFAULT 'approved', -- Review state: pending, approved, rejected
+    review_notes TEXT, -- Reviewer's notes to the author
+    reviewed_by TEXT,
+    reviewed_at TIMESTAMP,
     UNIQUE(name, version),
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
 );
@@ -35,6 +44,7 @@ CREATE TABLE IF NOT EXISTS module_requests (
     duplicate_of INTEGER, -- ID of the original request if this is a duplicate
     notes TEXT, -- Admin notes about the request
     fulfilled_by_module TEXT, -- Module name that fulfills this request
+    requested_by TEXT, -- Username of a logged-in requester, notified when the request is fulfilled
     FOREIGN KEY (duplicate_of) REFERENCES module_requests(id)
 );
 
@@ -107,3 +117,129 @@ CREATE TABLE IF NOT EXISTS install_scripts (
 
 CREATE INDEX IF NOT EXISTS idx_install_scripts_is_active ON install_scripts(is_active);
 CREATE INDEX IF NOT EXISTS idx_install_scripts_uploaded_at ON install_scripts(uploaded_at DESC);
+
+-- Opt-in execution reports sent by Clio after running a module
+CREATE TABLE IF NOT EXISTS module_run_stats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    module_name TEXT NOT NULL,
+    module_version TEXT,
+    success BOOLEAN NOT NULL,
+    duration_ms INTEGER DEFAULT 0,
+    failure_reason TEXT,
+    platform TEXT,
+    environment TEXT, -- JSON object of environment facts captured at flow start
+    reporter TEXT, -- SHA-256 of the reporting client's IP, for the per-module daily quota
+    reported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_run_stats_module_name ON module_run_stats(module_name);
+CREATE INDEX IF NOT EXISTS idx_module_run_stats_reported_at ON module_run_stats(reported_at DESC);
+
+-- Per-validation outcomes attached to a run report
+CREATE TABLE IF NOT EXISTS module_validation_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    module_name TEXT NOT NULL,
+    step TEXT NOT NULL,
+    check_command TEXT,
+    expected TEXT,
+    actual TEXT, -- truncated command output
+    passed BOOLEAN NOT NULL,
+    warn_only BOOLEAN DEFAULT 0,
+    FOREIGN KEY (run_id) REFERENCES module_run_stats(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_validation_results_module ON module_validation_results(module_name, passed);
+
+-- Structured diffs between consecutive module versions, computed at upload
+CREATE TABLE IF NOT EXISTS module_diffs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    module_name TEXT NOT NULL,
+    from_version TEXT NOT NULL,
+    to_version TEXT NOT NULL,
+    diff_json TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(module_name, from_version, to_version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_diffs_to ON module_diffs(module_name, to_version);
+
+-- Work-in-progress modules edited in the browser before publishing
+CREATE TABLE IF NOT EXISTS module_drafts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner TEXT NOT NULL, -- username
+    name TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_drafts_owner ON module_drafts(owner, updated_at DESC);
+
+-- User reports of malicious or broken modules, reviewed by admins
+CREATE TABLE IF NOT EXISTS module_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    module_name TEXT NOT NULL,
+    reporter TEXT NOT NULL, -- username
+    reason TEXT NOT NULL, -- malicious, broken, spam, other
+    details TEXT,
+    status TEXT NOT NULL DEFAULT 'open', -- open, dismissed, actioned
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_by TEXT,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_reports_status ON module_reports(status, module_name);
+
+-- One row per module download, with the platform Clio reports (if any)
+CREATE TABLE IF NOT EXISTS module_downloads (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    module_name TEXT NOT NULL,
+    module_version TEXT,
+    platform TEXT NOT NULL DEFAULT 'unknown',
+    downloaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_downloads_module ON module_downloads(module_name, platform);
+
+-- Managed category taxonomy shared by catalog commands and module tags
+CREATE TABLE IF NOT EXISTS categories (
+    name TEXT PRIMARY KEY, -- canonical, lowercase
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Alternative spellings that resolve to a canonical category
+CREATE TABLE IF NOT EXISTS category_synonyms (
+    synonym TEXT PRIMARY KEY,
+    category TEXT NOT NULL REFERENCES categories(name) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_synonyms_category ON category_synonyms(category);
+
+-- Short parameterized one-liners, lighter than modules; {{name}} placeholders
+-- are prompted for by Clio when the snippet is used
+CREATE TABLE IF NOT EXISTS snippets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    command TEXT NOT NULL,
+    description TEXT NOT NULL,
+    tags TEXT NOT NULL DEFAULT '[]', -- JSON array
+    placeholders TEXT NOT NULL DEFAULT '[]', -- JSON array, in order of first use
+    risk_level TEXT NOT NULL DEFAULT 'low',
+    uploaded_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_updated ON snippets(updated_at);
+
+-- Append-only log of module changes for delta sync; filled by the triggers in
+-- 002_module_change_triggers.sql. The id is the sync cursor.
+CREATE TABLE IF NOT EXISTS module_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    module_name TEXT NOT NULL,
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_module_changes_changed_at ON module_changes(changed_at);
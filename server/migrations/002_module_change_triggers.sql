@@ -0,0 +1,29 @@
+-- Record every change to a visible, approved module row: new versions,
+-- approvals, hides and deletes. Run after EnsureColumns, since the triggers
+-- reference columns added to old databases.
+CREATE TRIGGER IF NOT EXISTS module_changes_insert AFTER INSERT ON modules
+WHEN NEW.status = 'approved' AND NEW.hidden = 0
+BEGIN
+    INSERT INTO module_changes (module_name) VALUES (NEW.name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS module_changes_update
+AFTER UPDATE OF name, version, file_path, checksum, hidden, status ON modules
+WHEN (OLD.status = 'approved' AND OLD.hidden = 0) OR (NEW.status = 'approved' AND NEW.hidden = 0)
+BEGIN
+    INSERT INTO module_changes (module_name) VALUES (OLD.name);
+    INSERT INTO module_changes (module_name) SELECT NEW.name WHERE NEW.name != OLD.name;
+END;
+
+CREATE TRIGGER IF NOT EXISTS module_changes_delete AFTER DELETE ON modules
+WHEN OLD.status = 'approved' AND OLD.hidden = 0
+BEGIN
+    INSERT INTO module_changes (module_name) VALUES (OLD.name);
+END;
+
+-- Databases created before the log existed start it with one entry per module
+INSERT INTO module_changes (module_name, changed_at)
+SELECT name, MAX(uploaded_at) FROM modules
+WHERE status = 'approved' AND hidden = 0 AND NOT EXISTS (SELECT 1 FROM module_changes)
+GROUP BY name
+ORDER BY MAX(uploaded_at);
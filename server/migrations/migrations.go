@@ -1,7 +1,9 @@
 package migrations
 
 import (
+	"database/sql"
 	"embed"
+	"fmt"
 )
 
 //go:embed *.sql
@@ -15,3 +17,78 @@ func GetInitialSchema() (string, error) {
 	}
 	return string(data), nil
 }
+
+// EnsureChangeTriggers installs the triggers that fill module_changes and
+// backfills the log for databases created before it existed
+func EnsureChangeTriggers(db *sql.DB) error {
+	data, err := content.ReadFile("002_module_change_triggers.sql")
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(string(data))
+	return err
+}
+
+// Column describes a column added to an existing table after it was first created
+type Column struct {
+	Table      string
+	Name       string
+	Definition string
+}
+
+// AddedColumns must mirror columns added to CREATE TABLE statements in the
+// initial schema, so databases created before the change get them too.
+var AddedColumns = []Column{
+	{Table: "modules", Name: "risk_level", Definition: "TEXT DEFAULT 'low'"},
+	{Table: "modules", Name: "hidden", Definition: "BOOLEAN DEFAULT 0"},
+	{Table: "modules", Name: "provides", Definition: "TEXT DEFAULT '[]'"},
+	{Table: "modules", Name: "checksum", Definition: "TEXT"},
+	{Table: "modules", Name: "status", Definition: "TEXT DEFAULT 'approved'"},
+	{Table: "modules", Name: "review_notes", Definition: "TEXT"},
+	{Table: "modules", Name: "reviewed_by", Definition: "TEXT"},
+	{Table: "modules", Name: "reviewed_at", Definition: "TIMESTAMP"},
+	{Table: "modules", Name: "risk_report", Definition: "TEXT"},
+	{Table: "module_run_stats", Name: "environment", Definition: "TEXT"},
+	{Table: "module_run_stats", Name: "reporter", Definition: "TEXT"},
+	{Table: "module_requests", Name: "requested_by", Definition: "TEXT"},
+}
+
+// EnsureColumns adds any AddedColumns missing from existing tables
+func EnsureColumns(db *sql.DB) error {
+	for _, col := range AddedColumns {
+		exists, err := columnExists(db, col.Table, col.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.Table, col.Name, col.Definition)
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", col.Table, col.Name, err)
+		}
+	}
+	return nil
+}
+
+// columnExists checks PRAGMA table_info for a column name
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,184 @@
+package moddiff
+
+import (
+	"sort"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+// Change kinds for steps
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// StepChange describes one step that differs between two module versions
+type StepChange struct {
+	Flow       string `json:"flow"`
+	Step       string `json:"step"`
+	Change     string `json:"change"`
+	OldCommand string `json:"old_command,omitempty"`
+	NewCommand string `json:"new_command,omitempty"`
+	// CommandChanged is true when the executed command itself differs,
+	// as opposed to only the message, next step or validation
+	CommandChanged bool `json:"command_changed"`
+}
+
+// Diff is the structured difference between two versions of a module
+type Diff struct {
+	FromVersion     string       `json:"from_version"`
+	ToVersion       string       `json:"to_version"`
+	AddedTags       []string     `json:"added_tags"`
+	RemovedTags     []string     `json:"removed_tags"`
+	AddedFlows      []string     `json:"added_flows"`
+	RemovedFlows    []string     `json:"removed_flows"`
+	Steps           []StepChange `json:"steps"`
+	CommandsChanged int          `json:"commands_changed"`
+}
+
+// Compare returns what changed going from oldModule to newModule
+func Compare(oldModule, newModule *models.Module) Diff {
+	d := Diff{
+		FromVersion: oldModule.Version,
+		ToVersion:   newModule.Version,
+		Steps:       []StepChange{},
+	}
+	d.AddedTags, d.RemovedTags = setDiff(oldModule.Tags, newModule.Tags)
+	d.AddedFlows, d.RemovedFlows = setDiff(keys(oldModule.Flows), keys(newModule.Flows))
+
+	flowNames := map[string]bool{}
+	for name := range oldModule.Flows {
+		flowNames[name] = true
+	}
+	for name := range newModule.Flows {
+		flowNames[name] = true
+	}
+
+	for flowName := range flowNames {
+		oldSteps := stepsOf(oldModule.Flows[flowName])
+		newSteps := stepsOf(newModule.Flows[flowName])
+
+		for key, oldStep := range oldSteps {
+			newStep, ok := newSteps[key]
+			if !ok {
+				d.Steps = append(d.Steps, StepChange{
+					Flow: flowName, Step: key, Change: Removed,
+					OldCommand: oldStep.Command, CommandChanged: oldStep.Command != "",
+				})
+				continue
+			}
+			if !stepEqual(oldStep, newStep) {
+				d.Steps = append(d.Steps, StepChange{
+					Flow: flowName, Step: key, Change: Modified,
+					OldCommand: oldStep.Command, NewCommand: newStep.Command,
+					CommandChanged: oldStep.Command != newStep.Command,
+				})
+			}
+		}
+		for key, newStep := range newSteps {
+			if _, ok := oldSteps[key]; !ok {
+				d.Steps = append(d.Steps, StepChange{
+					Flow: flowName, Step: key, Change: Added,
+					NewCommand: newStep.Command, CommandChanged: newStep.Command != "",
+				})
+			}
+		}
+	}
+
+	sort.Slice(d.Steps, func(i, j int) bool {
+		a, b := d.Steps[i], d.Steps[j]
+		if a.Flow != b.Flow {
+			return a.Flow < b.Flow
+		}
+		return a.Step < b.Step
+	})
+	for _, s := range d.Steps {
+		if s.CommandChanged {
+			d.CommandsChanged++
+		}
+	}
+
+	return d
+}
+
+func stepsOf(flow *models.Flow) map[string]*models.Step {
+	steps := map[string]*models.Step{}
+	if flow == nil {
+		return steps
+	}
+	for key, step := range flow.Steps {
+		if step != nil {
+			steps[key] = step
+		}
+	}
+	return steps
+}
+
+func stepEqual(a, b *models.Step) bool {
+	if a.Type != b.Type || a.Message != b.Message || a.Command != b.Command || a.Rollback != b.Rollback || a.SkipIf != b.SkipIf ||
+		a.RunModule != b.RunModule || a.Flow != b.Flow || a.Next != b.Next || a.BasedOn != b.BasedOn ||
+		a.Background != b.Background || a.PIDKey != b.PIDKey || a.Process != b.Process ||
+		a.RequiresNetwork != b.RequiresNetwork || a.OnOffline != b.OnOffline || a.Cwd != b.Cwd ||
+		a.OnFailure != b.OnFailure || a.MaxRetries+a.Retries != b.MaxRetries+b.Retries || a.RetryDelay != b.RetryDelay || a.OnError != b.OnError ||
+		a.TimeoutSeconds != b.TimeoutSeconds ||
+		a.Var != b.Var || a.Pattern != b.Pattern || a.Default != b.Default || a.Secret != b.Secret {
+		return false
+	}
+	if len(a.Map) != len(b.Map) || len(a.Validate) != len(b.Validate) || len(a.Env) != len(b.Env) {
+		return false
+	}
+	for k, v := range a.Map {
+		if b.Map[k] != v {
+			return false
+		}
+	}
+	for k, v := range a.Env {
+		if bv, ok := b.Env[k]; !ok || bv != v {
+			return false
+		}
+	}
+	for i := range a.Validate {
+		if a.Validate[i] != b.Validate[i] {
+			return false
+		}
+	}
+	if (a.Condition == nil) != (b.Condition == nil) {
+		return false
+	}
+	return a.Condition == nil || *a.Condition == *b.Condition
+}
+
+func keys(flows map[string]*models.Flow) []string {
+	names := make([]string, 0, len(flows))
+	for name := range flows {
+		names = append(names, name)
+	}
+	return names
+}
+
+// setDiff returns the sorted items only in b (added) and only in a (removed)
+func setDiff(a, b []string) (added, removed []string) {
+	inA := map[string]bool{}
+	inB := map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+	for _, s := range b {
+		inB[s] = true
+	}
+	added, removed = []string{}, []string{}
+	for s := range inB {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range inA {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,69 @@
+package moddiff
+
+import (
+	"testing"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+func TestCompare(t *testing.T) {
+	oldModule := &models.Module{
+		Version: "1.0.0",
+		Tags:    []string{"git", "vcs"},
+		Flows: map[string]*models.Flow{
+			"main": {Start: "install", Steps: map[string]*models.Step{
+				"install": {Type: "action", Command: "apt-get install -y git", Next: "done"},
+				"check":   {Type: "action", Command: "git --version"},
+				"done":    {Type: "terminal", Message: "Done"},
+			}},
+		},
+	}
+	newModule := &models.Module{
+		Version: "1.1.0",
+		Tags:    []string{"git", "setup"},
+		Flows: map[string]*models.Flow{
+			"main": {Start: "install", Steps: map[string]*models.Step{
+				"install": {Type: "action", Command: "sudo apt-get install -y git", Next: "done"},
+				"config":  {Type: "action", Command: "git config --global init.defaultBranch main"},
+				"done":    {Type: "terminal", Message: "All done"},
+			}},
+		},
+	}
+
+	d := Compare(oldModule, newModule)
+
+	if d.FromVersion != "1.0.0" || d.ToVersion != "1.1.0" {
+		t.Errorf("versions = %s -> %s", d.FromVersion, d.ToVersion)
+	}
+	if len(d.AddedTags) != 1 || d.AddedTags[0] != "setup" || len(d.RemovedTags) != 1 || d.RemovedTags[0] != "vcs" {
+		t.Errorf("tags added=%v removed=%v", d.AddedTags, d.RemovedTags)
+	}
+
+	want := map[string]string{"check": Removed, "config": Added, "done": Modified, "install": Modified}
+	if len(d.Steps) != len(want) {
+		t.Fatalf("got %d step changes, want %d: %+v", len(d.Steps), len(want), d.Steps)
+	}
+	for _, s := range d.Steps {
+		if want[s.Step] != s.Change {
+			t.Errorf("step %s change = %s, want %s", s.Step, s.Change, want[s.Step])
+		}
+	}
+	// install, check and config touch commands; done only changes its message
+	if d.CommandsChanged != 3 {
+		t.Errorf("CommandsChanged = %d, want 3", d.CommandsChanged)
+	}
+}
+
+func TestCompareIdentical(t *testing.T) {
+	m := &models.Module{
+		Version: "1.0.0",
+		Flows: map[string]*models.Flow{
+			"main": {Start: "a", Steps: map[string]*models.Step{
+				"a": {Type: "action", Command: "ls", Validate: []models.Validation{{CheckCommand: "true"}}},
+			}},
+		},
+	}
+	if d := Compare(m, m); len(d.Steps) != 0 || d.CommandsChanged != 0 {
+		t.Errorf("identical modules produced diff: %+v", d)
+	}
+}
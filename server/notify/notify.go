@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Event types sent by the registry
+const (
+	ModuleReported   = "module.reported"
+	ModuleHidden     = "module.hidden"
+	ModuleModerated  = "module.moderated"
+	ModuleSubmitted  = "module.submitted"
+	ModuleReviewed   = "module.reviewed"
+	RequestFulfilled = "request.fulfilled"
+)
+
+// Event is one notification. To lists email recipients (e.g. the module
+// owner); backends that do not address individuals ignore it.
+type Event struct {
+	Type    string            `json:"type"`
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	To      []string          `json:"to,omitempty"`
+	Data    map[string]string `json:"data,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// Notifier delivers events to users and admins
+type Notifier interface {
+	Notify(Event) error
+}
+
+// Config selects and configures backends. Unset backends are skipped.
+type Config struct {
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	SMTPFrom     string
+	// AdminEmail receives a copy of every event sent over SMTP
+	AdminEmail string
+	WebhookURL string
+}
+
+// New returns a Notifier for every configured backend, or a no-op notifier
+func New(cfg Config) Notifier {
+	var backends multi
+	if cfg.SMTPHost != "" {
+		port := cfg.SMTPPort
+		if port == "" {
+			port = "587"
+		}
+		from := cfg.SMTPFrom
+		if from == "" {
+			from = cfg.SMTPUser
+		}
+		backends = append(backends, &SMTP{
+			Addr:       net.JoinHostPort(cfg.SMTPHost, port),
+			Host:       cfg.SMTPHost,
+			User:       cfg.SMTPUser,
+			Password:   cfg.SMTPPassword,
+			From:       from,
+			AdminEmail: cfg.AdminEmail,
+		})
+	}
+	if cfg.WebhookURL != "" {
+		backends = append(backends, &Webhook{URL: cfg.WebhookURL, Client: &http.Client{Timeout: 10 * time.Second}})
+	}
+	if len(backends) == 0 {
+		return Nop{}
+	}
+	return backends
+}
+
+// Nop discards events
+type Nop struct{}
+
+// Notify implements Notifier
+func (Nop) Notify(Event) error { return nil }
+
+// multi fans an event out to several backends
+type multi []Notifier
+
+func (m multi) Notify(ev Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SMTP sends events as plain-text email, one message per recipient so
+// addresses are never disclosed to each other
+type SMTP struct {
+	Addr       string
+	Host       string
+	User       string
+	Password   string
+	From       string
+	AdminEmail string
+	// send is swapped out in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Notify implements Notifier
+func (s *SMTP) Notify(ev Event) error {
+	to := append([]string{}, ev.To...)
+	if s.AdminEmail != "" && !slices.Contains(to, s.AdminEmail) {
+		to = append(to, s.AdminEmail)
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Password, s.Host)
+	}
+	send := s.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	var errs []error
+	for _, rcpt := range to {
+		if err := send(s.Addr, auth, s.From, []string{rcpt}, s.message(ev, rcpt)); err != nil {
+			errs = append(errs, fmt.Errorf("smtp: %s: %w", rcpt, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SMTP) message(ev Event, to string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", sanitizeHeader(to))
+	fmt.Fprintf(&b, "Subject: [CLIPilot Registry] %s\r\n", sanitizeHeader(ev.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(ev.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+func sanitizeHeader(v string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(v)
+}
+
+// Webhook POSTs events as JSON to a URL (Slack/Discord relays, CI, etc.)
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements Notifier
+func (wh *Webhook) Notify(ev Event) error {
+	payload := ev
+	payload.To = nil // don't leak user email addresses to third parties
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := wh.Client.Post(wh.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestNewWithoutBackendsIsNop(t *testing.T) {
+	if _, ok := New(Config{}).(Nop); !ok {
+		t.Fatal("expected Nop when nothing is configured")
+	}
+}
+
+func TestWebhookStripsRecipients(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	n := New(Config{WebhookURL: srv.URL})
+	err := n.Notify(Event{Type: ModuleHidden, Subject: "hidden", To: []string{"owner@example.com"}})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got.Type != ModuleHidden || len(got.To) != 0 {
+		t.Fatalf("webhook received %+v", got)
+	}
+}
+
+func TestSMTPAddsAdminAndFormatsMessage(t *testing.T) {
+	var gotTo []string
+	var gotMsg string
+	s := &SMTP{
+		Addr: "mail:587", Host: "mail", From: "registry@example.com", AdminEmail: "admin@example.com",
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			if len(to) != 1 {
+				t.Errorf("message addressed to %v, want one recipient", to)
+			}
+			if !strings.Contains(string(msg), "\r\nTo: "+to[0]+"\r\n") {
+				t.Errorf("To header does not name only %v: %q", to, msg)
+			}
+			gotTo, gotMsg = append(gotTo, to...), string(msg)
+			return nil
+		},
+	}
+
+	ev := Event{Subject: "Hi\r\nBcc: evil@example.com", Body: "line1\nline2", To: []string{"owner@example.com", "coowner@example.com"}}
+	if err := s.Notify(ev); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(gotTo) != 3 || gotTo[2] != "admin@example.com" {
+		t.Fatalf("recipients = %v", gotTo)
+	}
+	if strings.Contains(gotMsg, "\r\nBcc:") {
+		t.Fatal("subject allowed header injection")
+	}
+	if !strings.Contains(gotMsg, "line1\r\nline2") {
+		t.Fatalf("body not CRLF-normalised: %q", gotMsg)
+	}
+}
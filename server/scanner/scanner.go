@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+// Risk levels, ordered from least to most dangerous
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Finding is one dangerous pattern matched in a module command
+type Finding struct {
+	Flow     string `json:"flow"`
+	Step     string `json:"step"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Command  string `json:"command"`
+}
+
+// Report is the result of scanning a whole module
+type Report struct {
+	Level    string    `json:"level"`
+	Findings []Finding `json:"findings"`
+}
+
+type rule struct {
+	name     string
+	severity string
+	message  string
+	pattern  *regexp.Regexp
+}
+
+// rules are matched against every step command and validation check command
+var rules = []rule{
+	{"rm-root", RiskHigh, "recursively deletes the root or home directory",
+		regexp.MustCompile(`\brm\s+(-{1,2}[\w-]+\s+)*-([a-zA-Z]*[rR][a-zA-Z]*|-recursive)\s+(-{1,2}[\w-]+\s+)*(/\*?|~/?|\$HOME/?)(\s|$|[;&|])`)},
+	{"fork-bomb", RiskHigh, "fork bomb",
+		regexp.MustCompile(`:\s*\(\s*\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`)},
+	{"mkfs", RiskHigh, "formats a filesystem",
+		regexp.MustCompile(`\bmkfs(\.[a-z0-9]+)?\b`)},
+	{"dd-device", RiskHigh, "writes directly to a block device",
+		regexp.MustCompile(`\bdd\b[^|;&]*\bof=/dev/(sd|hd|nvme|mmcblk|vd|disk)`)},
+	{"redirect-device", RiskHigh, "overwrites a block device",
+		regexp.MustCompile(`>\s*/dev/(sd|hd|nvme|mmcblk|vd|disk)[a-z0-9]*`)},
+	{"base64-exec", RiskHigh, "decodes and executes a hidden payload",
+		regexp.MustCompile(`base64\s+(-d|--decode|-D)\b[^;&]*\|\s*(sudo\s+)?(ba|z|da)?sh\b`)},
+	{"eval-decode", RiskHigh, "evaluates a decoded payload",
+		regexp.MustCompile(`\beval\s+["']?\$\(\s*(echo|printf)[^)]*\|\s*base64\s+(-d|--decode)`)},
+	{"pipe-to-shell", RiskMedium, "pipes a remote download straight into a shell",
+		regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(ba|z|da)?sh\b`)},
+	{"chmod-777-root", RiskMedium, "makes system paths world-writable",
+		regexp.MustCompile(`\bchmod\s+(-R\s+)?0?777\s+/(\s|$|etc|usr|bin|var)`)},
+	{"history-wipe", RiskMedium, "erases shell history",
+		regexp.MustCompile(`(history\s+-c|>\s*~/\.(bash|zsh)_history|unset\s+HISTFILE)`)},
+}
+
+// loaderEnv are environment variables that let a step inject code into
+// every program it runs
+var loaderEnv = map[string]bool{
+	"LD_PRELOAD":            true,
+	"LD_AUDIT":              true,
+	"LD_LIBRARY_PATH":       true,
+	"DYLD_INSERT_LIBRARIES": true,
+}
+
+// ScanCommand returns the rules a single command matches
+func ScanCommand(command string) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		if r.pattern.MatchString(command) {
+			findings = append(findings, Finding{
+				Rule:     r.name,
+				Severity: r.severity,
+				Message:  r.message,
+				Command:  command,
+			})
+		}
+	}
+	return findings
+}
+
+// ScanModule scans the module environment and every step command, rollback,
+// skip_if check, validation check and step environment in every flow
+func ScanModule(module *models.Module) Report {
+	report := Report{Level: RiskLow, Findings: []Finding{}}
+
+	for name := range module.Env {
+		if loaderEnv[name] {
+			report.Findings = append(report.Findings, Finding{
+				Rule: "env-loader", Severity: RiskMedium,
+				Message: "sets " + name + " for every step to override the dynamic loader",
+			})
+			report.Level = maxLevel(report.Level, RiskMedium)
+		}
+	}
+
+	for flowName, flow := range module.Flows {
+		if flow == nil {
+			continue
+		}
+		for stepKey, step := range flow.Steps {
+			if step == nil {
+				continue
+			}
+			commands := []string{step.Command, step.Rollback, step.SkipIf}
+			for _, v := range step.Validate {
+				commands = append(commands, v.CheckCommand)
+			}
+			for name := range step.Env {
+				if loaderEnv[name] {
+					report.Findings = append(report.Findings, Finding{
+						Flow: flowName, Step: stepKey, Rule: "env-loader", Severity: RiskMedium,
+						Message: "sets " + name + " to override the dynamic loader", Command: step.Command,
+					})
+					report.Level = maxLevel(report.Level, RiskMedium)
+				}
+			}
+			for _, cmd := range commands {
+				if cmd == "" {
+					continue
+				}
+				for _, f := range ScanCommand(cmd) {
+					f.Flow = flowName
+					f.Step = stepKey
+					report.Findings = append(report.Findings, f)
+					report.Level = maxLevel(report.Level, f.Severity)
+				}
+			}
+		}
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Flow != b.Flow {
+			return a.Flow < b.Flow
+		}
+		if a.Step != b.Step {
+			return a.Step < b.Step
+		}
+		return a.Rule < b.Rule
+	})
+
+	return report
+}
+
+func maxLevel(a, b string) string {
+	rank := map[string]int{RiskLow: 0, RiskMedium: 1, RiskHigh: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
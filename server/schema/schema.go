@@ -0,0 +1,98 @@
+// Package schema generates a JSON Schema for the module YAML format from
+// internal/models, for editors (yaml-language-server) and client-side validation.
+package schema
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/themobileprof/clipilot/internal/models"
+)
+
+// ID is the public URL the schema is served from
+const ID = "https://clipilot.themobileprof.com/api/v1/schema/module.json"
+
+// enums restrict string fields to the values upload validation accepts,
+// keyed by "<Go type>.<yaml name>"
+var enums = map[string][]string{
+	"Step.type":          {"instruction", "action", "branch", "terminal", "process", "prompt", "goto_flow"},
+	"Step.process":       {"status", "stop"},
+	"Step.on_offline":    {"skip", "wait"},
+	"Step.on_failure":    {"retry", "skip", "abort"},
+	"Condition.operator": {"eq", "ne", "gt", "lt", "contains"},
+}
+
+// patterns are regular expressions string fields must match
+var patterns = map[string]string{
+	"Module.name":    `^[a-z0-9_]+$`,
+	"Module.version": `^\d+\.\d+\.\d+$`,
+	"Step.pid_key":   `^[a-z0-9_]+$`,
+	"Step.var":       `^[a-z0-9_]+$`,
+}
+
+// required lists the fields upload validation insists on
+var required = map[string][]string{
+	"Module": {"name", "version", "description", "tags", "flows"},
+	"Flow":   {"start", "steps"},
+	"Step":   {"type"},
+}
+
+// Module returns the JSON Schema (draft 2020-12) of a module file
+func Module() map[string]interface{} {
+	s := object(reflect.TypeOf(models.Module{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = ID
+	s["title"] = "CLIPilot module"
+	return s
+}
+
+func object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		prop := typeSchema(f.Type)
+		key := t.Name() + "." + name
+		if values, ok := enums[key]; ok {
+			prop["enum"] = values
+		}
+		if pattern, ok := patterns[key]; ok {
+			prop["pattern"] = pattern
+		}
+		props[name] = prop
+	}
+
+	// Unknown keys stay allowed: the server ignores them and existing
+	// modules carry client-only extras such as branches and estimated_time
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if req, ok := required[t.Name()]; ok {
+		s["required"] = req
+	}
+	return s
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return object(t)
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestModuleSchema(t *testing.T) {
+	s := Module()
+	if _, err := json.Marshal(s); err != nil {
+		t.Fatalf("schema does not encode: %v", err)
+	}
+
+	props := s["properties"].(map[string]interface{})
+	if props["name"].(map[string]interface{})["pattern"] != `^[a-z0-9_]+$` {
+		t.Fatalf("name pattern missing: %v", props["name"])
+	}
+
+	flows := props["flows"].(map[string]interface{})
+	flow := flows["additionalProperties"].(map[string]interface{})
+	steps := flow["properties"].(map[string]interface{})["steps"].(map[string]interface{})
+	step := steps["additionalProperties"].(map[string]interface{})
+	stepProps := step["properties"].(map[string]interface{})
+	if _, ok := stepProps["key"]; ok {
+		t.Fatal("yaml:\"-\" field key should not be in the schema")
+	}
+	types := stepProps["type"].(map[string]interface{})["enum"].([]string)
+	if len(types) == 0 || types[0] != "instruction" {
+		t.Fatalf("step type enum = %v", types)
+	}
+	if stepProps["env"].(map[string]interface{})["type"] != "object" {
+		t.Fatalf("env should be an object: %v", stepProps["env"])
+	}
+}
@@ -0,0 +1,187 @@
+// Package seed generates synthetic registry data for load and performance testing.
+package seed
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/themobileprof/clipilot/internal/models"
+	"github.com/themobileprof/clipilot/server/catalog"
+	"github.com/themobileprof/clipilot/server/scanner"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Uploader is the uploaded_by value on generated modules, so seeded data can
+// be told apart and removed with DELETE FROM modules WHERE uploaded_by = 'seed'
+const Uploader = "seed"
+
+// Options controls how much data Run generates
+type Options struct {
+	Modules    int
+	Requests   int
+	UploadsDir string
+	RandSeed   int64 // fixed seeds give reproducible datasets
+}
+
+// Result reports what Run inserted
+type Result struct {
+	Modules  int
+	Versions int
+	Requests int
+}
+
+var (
+	actions   = []string{"setup", "install", "configure", "backup", "cleanup", "monitor", "deploy", "debug", "update", "secure"}
+	platforms = []string{"termux", "ubuntu", "debian", "fedora", "arch", "macos", "alpine"}
+	authors   = []string{"ada", "tunde", "chioma", "kemi", "emeka", "zainab", "musa", "ngozi"}
+	statuses  = []string{"pending", "pending", "pending", "in_progress", "completed", "duplicate"}
+	phrasings = []string{
+		"how do I %s %s on %s",
+		"%s %s for %s",
+		"need help to %s %s on my %s phone",
+		"%s %s %s step by step",
+		"wetin I go do to %s %s for %s",
+	}
+)
+
+// Run inserts synthetic modules (with one to three versions each, YAML files
+// written to UploadsDir) and module requests in a single transaction
+func Run(db *sql.DB, opts Options) (Result, error) {
+	var res Result
+	rng := rand.New(rand.NewSource(opts.RandSeed))
+	commands := catalog.All()
+	if len(commands) == 0 {
+		return res, fmt.Errorf("command catalog is empty")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	moduleStmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO modules (name, version, description, author, tags, uploaded_by, file_path, original_filename, downloads, risk_level, checksum, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return res, err
+	}
+	defer moduleStmt.Close()
+
+	requestStmt, err := tx.Prepare(`
+		INSERT INTO module_requests (query, user_context, ip_address, user_agent, created_at, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return res, err
+	}
+	defer requestStmt.Close()
+
+	now := time.Now().UTC()
+	for i := 0; i < opts.Modules; i++ {
+		cmd := commands[rng.Intn(len(commands))]
+		action := actions[rng.Intn(len(actions))]
+		platform := platforms[rng.Intn(len(platforms))]
+		name := fmt.Sprintf("%s_%s_%s_%d", action, sanitizeName(cmd.Name), platform, i)
+		author := authors[rng.Intn(len(authors))]
+		uploadedAt := now.Add(-time.Duration(rng.Intn(365*24)) * time.Hour)
+
+		versions := 1 + rng.Intn(3)
+		for v := 0; v < versions; v++ {
+			module := generateModule(rng, name, fmt.Sprintf("1.%d.0", v), action, platform, author, cmd, commands)
+			data, err := yaml.Marshal(module)
+			if err != nil {
+				return res, err
+			}
+			filename := fmt.Sprintf("%s-%s.yaml", module.Name, module.Version)
+			path := filepath.Join(opts.UploadsDir, "seed-"+filename)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				return res, err
+			}
+
+			tags := `["` + strings.Join(module.Tags, `","`) + `"]`
+			risk := scanner.ScanModule(module).Level
+			if _, err := moduleStmt.Exec(module.Name, module.Version, module.Description, author, tags, Uploader,
+				path, filename, rng.Intn(5000), risk, fmt.Sprintf("%x", sha256.Sum256(data)), uploadedAt.Format("2006-01-02 15:04:05")); err != nil {
+				return res, fmt.Errorf("insert module %s: %w", module.Name, err)
+			}
+			uploadedAt = uploadedAt.Add(time.Duration(1+rng.Intn(30*24)) * time.Hour)
+			res.Versions++
+		}
+		res.Modules++
+	}
+
+	for i := 0; i < opts.Requests; i++ {
+		cmd := commands[rng.Intn(len(commands))]
+		query := fmt.Sprintf(phrasings[rng.Intn(len(phrasings))],
+			actions[rng.Intn(len(actions))], cmd.Name, platforms[rng.Intn(len(platforms))])
+		createdAt := now.Add(-time.Duration(rng.Intn(180*24*60)) * time.Minute)
+		ip := fmt.Sprintf("10.%d.%d.%d", rng.Intn(256), rng.Intn(256), 1+rng.Intn(254))
+		if _, err := requestStmt.Exec(query, `{"os":"linux"}`, ip, "clio-seed/1.0",
+			createdAt.Format("2006-01-02 15:04:05"), statuses[rng.Intn(len(statuses))]); err != nil {
+			return res, fmt.Errorf("insert request: %w", err)
+		}
+		res.Requests++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// generateModule builds a small but valid module that walks through a few catalog commands
+func generateModule(rng *rand.Rand, name, version, action, platform, author string, main catalog.CommandEntry, commands []catalog.CommandEntry) *models.Module {
+	steps := map[string]*models.Step{}
+	stepCount := 2 + rng.Intn(4)
+	for s := 0; s < stepCount; s++ {
+		cmd := main
+		if s > 0 {
+			cmd = commands[rng.Intn(len(commands))]
+		}
+		key := fmt.Sprintf("step%d", s+1)
+		next := fmt.Sprintf("step%d", s+2)
+		if s == stepCount-1 {
+			next = "done"
+		}
+		steps[key] = &models.Step{
+			Type:    "action",
+			Message: cmd.Description,
+			Command: catalog.UseCase(cmd, "linux"),
+			Next:    next,
+		}
+	}
+	steps["done"] = &models.Step{Type: "terminal", Message: "Finished"}
+
+	category := main.Category
+	if category == "" {
+		category = "system"
+	}
+	return &models.Module{
+		ID:          name,
+		Name:        name,
+		Version:     version,
+		Description: fmt.Sprintf("%s %s on %s: %s", strings.ToUpper(action[:1])+action[1:], main.Name, platform, main.Description),
+		Tags:        []string{action, platform, category, main.Name},
+		Flows: map[string]*models.Flow{
+			"main": {Start: "step1", Steps: steps},
+		},
+		Metadata: models.ModuleMetadata{Author: author, License: "MIT"},
+	}
+}
+
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(s))
+}
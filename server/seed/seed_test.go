@@ -0,0 +1,39 @@
+package seed
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/themobileprof/clipilot/server/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestRunInsertsModulesAndRequests(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	schema, err := migrations.GetInitialSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Run(db, Options{Modules: 5, Requests: 20, UploadsDir: t.TempDir(), RandSeed: 42})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var modules, requests int
+	db.QueryRow("SELECT COUNT(*) FROM modules WHERE uploaded_by = ?", Uploader).Scan(&modules)
+	db.QueryRow("SELECT COUNT(*) FROM module_requests").Scan(&requests)
+	if res.Modules != 5 || modules != res.Versions || requests != 20 {
+		t.Fatalf("result %+v, %d module rows, %d requests", res, modules, requests)
+	}
+}